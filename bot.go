@@ -0,0 +1,317 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ip_detector/config"
+	"ip_detector/detector"
+	"ip_detector/notifier"
+)
+
+const (
+	// botPollTimeout is how long a single getUpdates request waits for messages
+	botPollTimeout = 30 * time.Second
+	// botRetryDelay is the pause after a failed getUpdates request
+	botRetryDelay = 10 * time.Second
+	// botTimeFormat is used for timestamps in command replies
+	botTimeFormat = "2006-01-02 15:04:05 MST"
+	// defaultHistoryCount and maxHistoryCount bound the /history command
+	defaultHistoryCount = 5
+	maxHistoryCount     = 50
+)
+
+// checkSchedule tracks the daemon's last and next check for /status
+type checkSchedule struct {
+	Last time.Time
+	Next time.Time
+}
+
+// botCommand is a command received from a configured Telegram chat
+type botCommand struct {
+	Name    string   // without the leading slash or @botname suffix, e.g. "history"
+	Args    []string // whitespace-separated arguments
+	Message notifier.TelegramMessage
+}
+
+// telegramBot returns the Telegram notifier that answers bot commands, or nil
+// if bot commands are disabled or the channel is unusable
+func telegramBot(cfg *config.Config) *notifier.TelegramNotifier {
+	if cfg.TelegramBot == nil || !cfg.TelegramBot.Enabled {
+		return nil
+	}
+	bot, err := telegramChannel(cfg, cfg.TelegramBot.Channel)
+	if err != nil {
+		fmt.Printf("⚠️  Bot commands disabled: %v\n", err)
+		return nil
+	}
+	return bot
+}
+
+// telegramChannel returns the enabled telegram notifier with the given name,
+// defaulting to the "telegram" channel of the setup wizard
+func telegramChannel(cfg *config.Config, name string) (*notifier.TelegramNotifier, error) {
+	if name == "" {
+		name = "telegram"
+	}
+	for _, channel := range buildChannels(cfg) {
+		if channel.Name != name {
+			continue
+		}
+		if bot, ok := channel.Notifier.(*notifier.TelegramNotifier); ok {
+			return bot, nil
+		}
+		return nil, fmt.Errorf("notifier %s is not a telegram notifier", name)
+	}
+	return nil, fmt.Errorf("no enabled telegram notifier named %s", name)
+}
+
+// parseBotCommand extracts a command from a message text, returning false for
+// messages that are not commands
+func parseBotCommand(text string) (botCommand, bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return botCommand{}, false
+	}
+	name := strings.TrimPrefix(fields[0], "/")
+	if i := strings.Index(name, "@"); i >= 0 {
+		name = name[:i]
+	}
+	return botCommand{Name: strings.ToLower(name), Args: fields[1:]}, true
+}
+
+// pollBotCommands long-polls Telegram for commands and forwards those sent in
+// the configured chats. Messages sent before polling started are skipped, so a
+// restart does not replay old commands.
+func pollBotCommands(bot *notifier.TelegramNotifier, commands chan<- botCommand) {
+	started := time.Now().Unix()
+	var offset int64
+	for {
+		updates, err := bot.GetUpdates(offset, botPollTimeout)
+		if err != nil {
+			fmt.Printf("⚠️  Telegram bot: %v\n", err)
+			time.Sleep(botRetryDelay)
+			continue
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			msg := update.Message
+			if msg == nil || msg.Date < started {
+				continue
+			}
+			if !bot.IsConfiguredChat(msg.Chat.ID) {
+				fmt.Printf("⚠️  Telegram bot: ignoring message from chat %d\n", msg.Chat.ID)
+				continue
+			}
+			if cmd, ok := parseBotCommand(msg.Text); ok {
+				cmd.Message = *msg
+				commands <- cmd
+			}
+		}
+	}
+}
+
+// handleBotCommand runs a bot command and returns the text reply, if any. It
+// is called from the daemon loop, so it never runs concurrently with a
+// scheduled check.
+func handleBotCommand(cfg *config.Config, hostname string, bot *notifier.TelegramNotifier, cmd botCommand, schedule *checkSchedule) string {
+	switch cmd.Name {
+	case "ip":
+		return botIPReply(cfg, hostname)
+	case "check":
+		schedule.Last = time.Now()
+		return botCheckReply(cfg, hostname)
+	case "history":
+		return botHistoryReply(cmd.Args)
+	case "status":
+		return botStatusReply(cfg, hostname, schedule)
+	case "chart":
+		return botChartReply(cfg, hostname, bot, cmd)
+	case "mute":
+		return botMuteReply(cfg, cmd.Args)
+	case "unmute":
+		return botMuteReply(cfg, []string{"off"})
+	case "start", "help":
+		return "Commands:\n" +
+			"/ip - last known addresses\n" +
+			"/check - detect the addresses now\n" +
+			"/history [n] - last n address changes\n" +
+			"/status - last check, detection services and next check\n" +
+			"/chart [week|month] - chart of the addresses held\n" +
+			"/mute 2h - suppress notifications for a while (/mute off to undo)"
+	}
+	return fmt.Sprintf("Unknown command /%s, send /help for a list.", cmd.Name)
+}
+
+// describeAddress formats an address with its label for a reply
+func describeAddress(cfg *config.Config, ip string) string {
+	if ip == "" {
+		return "not available"
+	}
+	if label := cfg.LabelFor(ip); label != "" {
+		return fmt.Sprintf("%s (%s)", ip, label)
+	}
+	return ip
+}
+
+// formatStoredTime formats an RFC 3339 timestamp from the configuration
+func formatStoredTime(value string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return "never"
+	}
+	return t.Local().Format(botTimeFormat)
+}
+
+// botIPReply lists the last known addresses
+func botIPReply(cfg *config.Config, hostname string) string {
+	return fmt.Sprintf("🖥️ %s\n📍 IPv4: %s\n📍 IPv6: %s\n🔄 Last change: %s",
+		hostname, describeAddress(cfg, cfg.LastKnownIPv4), describeAddress(cfg, cfg.LastKnownIPv6),
+		formatStoredTime(cfg.LastChanged))
+}
+
+// botCheckReply runs a check right away; a change is notified as usual
+func botCheckReply(cfg *config.Config, hostname string) string {
+	lastChanged := cfg.LastChanged
+	if err := checkAndNotify(cfg, hostname); err != nil {
+		return fmt.Sprintf("❌ Check failed: %v", err)
+	}
+
+	result := "✅ No change."
+	if cfg.LastChanged != lastChanged {
+		result = "🔄 Address changed."
+	}
+	return result + "\n\n" + botIPReply(cfg, hostname)
+}
+
+// botHistoryReply lists the most recent address changes
+func botHistoryReply(args []string) string {
+	count := defaultHistoryCount
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return "Usage: /history [n]"
+		}
+		count = n
+	}
+	if count > maxHistoryCount {
+		count = maxHistoryCount
+	}
+
+	history, err := config.LoadHistory()
+	if err != nil {
+		return fmt.Sprintf("❌ Failed to load history: %v", err)
+	}
+	if len(history) == 0 {
+		return "No address changes recorded."
+	}
+	if len(history) < count {
+		count = len(history)
+	}
+
+	lines := []string{fmt.Sprintf("Last %d change(s):", count)}
+	for _, entry := range history[:count] {
+		from := entry.OldIP
+		if from == "" {
+			from = "-"
+		}
+		line := fmt.Sprintf("%s %s: %s → %s", formatStoredTime(entry.Timestamp), entry.Type, from, entry.NewIP)
+		if entry.NewLabel != "" {
+			line += " (" + entry.NewLabel + ")"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// botStatusReply reports the check schedule, detection health and the state
+// of every detection service
+func botStatusReply(cfg *config.Config, hostname string, schedule *checkSchedule) string {
+	if err := configureServices(cfg); err != nil {
+		return fmt.Sprintf("❌ %v", err)
+	}
+
+	health := "✅ working"
+	if cfg.DetectionFailing {
+		health = "❌ failing"
+	}
+	lines := []string{
+		"🖥️ " + hostname,
+		"🕐 Last check: " + schedule.Last.Local().Format(botTimeFormat),
+		"⏭️ Next check: " + schedule.Next.Local().Format(botTimeFormat),
+		"🔎 Detection: " + health,
+	}
+	if until, err := time.Parse(time.RFC3339, cfg.MutedUntil); err == nil && time.Now().Before(until) {
+		lines = append(lines, "🔕 Muted until "+until.Local().Format(botTimeFormat))
+	}
+
+	// Probe every service for IPv4 in parallel
+	results := make([]string, len(detector.Services))
+	var wg sync.WaitGroup
+	for i := range detector.Services {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			service := &detector.Services[i]
+			mark := "✅"
+			if _, err := detector.DetectIPv4(service); err != nil {
+				mark = "❌"
+			}
+			if service.Name == cfg.SelectedService {
+				results[i] = fmt.Sprintf("    %s %s (selected)", mark, service.Name)
+			} else {
+				results[i] = fmt.Sprintf("    %s %s", mark, service.Name)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	lines = append(lines, "Services:")
+	return strings.Join(append(lines, results...), "\n")
+}
+
+// botMuteReply mutes event notifications for a duration, or unmutes them
+func botMuteReply(cfg *config.Config, args []string) string {
+	if len(args) != 1 {
+		return "Usage: /mute 2h (or /mute off)"
+	}
+
+	reply := "🔔 Notifications unmuted."
+	if args[0] == "off" {
+		cfg.MutedUntil = ""
+	} else {
+		duration, err := time.ParseDuration(args[0])
+		if err != nil || duration <= 0 {
+			return "Usage: /mute 2h (or /mute off)"
+		}
+		until := time.Now().Add(duration)
+		cfg.MutedUntil = until.Format(time.RFC3339)
+		reply = "🔕 Notifications muted until " + until.Local().Format(botTimeFormat) + "."
+	}
+
+	if err := cfg.Save(); err != nil {
+		return fmt.Sprintf("❌ Failed to save configuration: %v", err)
+	}
+	return reply
+}
+
+// botChartReply sends the history chart as a photo
+func botChartReply(cfg *config.Config, hostname string, bot *notifier.TelegramNotifier, cmd botCommand) string {
+	period := ""
+	if len(cmd.Args) > 0 {
+		period = cmd.Args[0]
+	}
+
+	photo, caption, err := buildChart(cfg, hostname, period, time.Now())
+	if err != nil {
+		return fmt.Sprintf("❌ %v", err)
+	}
+	if err := bot.ReplyPhoto(cmd.Message, photo, caption); err != nil {
+		return fmt.Sprintf("❌ Failed to send chart: %v", err)
+	}
+	return ""
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"ip_detector/chart"
+	"ip_detector/config"
+	"ip_detector/notifier"
+)
+
+// chartPeriods maps the supported chart periods to their length
+var chartPeriods = map[string]time.Duration{
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+}
+
+// defaultChartInterval is how often a scheduled chart is sent by default
+const defaultChartInterval = 7 * 24 * time.Hour
+
+// chartRetryInterval is how long to wait before retrying a scheduled chart
+// that could not be sent to any chat
+const chartRetryInterval = time.Hour
+
+// buildChart renders the address history of the given period ("week" if
+// empty) and returns the PNG image and a caption explaining its colours
+func buildChart(cfg *config.Config, hostname, period string, now time.Time) ([]byte, string, error) {
+	if period == "" {
+		period = "week"
+	}
+	length, ok := chartPeriods[period]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown chart period %q (use week or month)", period)
+	}
+
+	history, err := config.LoadHistory()
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Label addresses with the current catalogue, like the stats
+	changes := make([]chart.Change, 0, len(history))
+	for _, entry := range history {
+		t, err := time.Parse(time.RFC3339, entry.Timestamp)
+		if err != nil {
+			continue
+		}
+		label := cfg.LabelFor(entry.NewIP)
+		if label == "" {
+			label = entry.NewLabel
+		}
+		changes = append(changes, chart.Change{Family: entry.Type, IP: entry.NewIP, Label: label, Time: t})
+	}
+
+	c, err := chart.Render(changes, now.Add(-length), now)
+	if err != nil {
+		return nil, "", err
+	}
+	return c.PNG, chartCaption(hostname, period, c), nil
+}
+
+// chartCaption lists the colour of every address, per lane
+func chartCaption(hostname, period string, c *chart.Chart) string {
+	lines := []string{
+		fmt.Sprintf("📈 IP history of %s, last %s", hostname, period),
+		fmt.Sprintf("%s → %s", c.Start.Format("2006-01-02 15:04"), c.End.Format("2006-01-02 15:04 MST")),
+		"Lanes: IPv4 (top), IPv6 (bottom); white lines mark midnight, black lines a change, grey no data.",
+	}
+	for _, family := range chart.Families {
+		name := strings.Replace(strings.ToUpper(family), "IPV", "IPv", 1)
+		lines = append(lines, "", fmt.Sprintf("%s: %d change(s)", name, c.Changes[family]))
+		for _, entry := range c.Legend {
+			if entry.Family != family {
+				continue
+			}
+			line := fmt.Sprintf("%s %s", entry.Emoji, entry.IP)
+			if entry.Label != "" {
+				line += " (" + entry.Label + ")"
+			}
+			lines = append(lines, line+" "+formatHeld(entry.Duration))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatHeld formats the time an address was held, e.g. "2d 5h"
+func formatHeld(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	hours := int((d % (24 * time.Hour)) / time.Hour)
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh", days, hours)
+	}
+	return fmt.Sprintf("%dh %dm", hours, int((d%time.Hour)/time.Minute))
+}
+
+// chartDue reports whether the scheduled chart should be sent
+func chartDue(cfg *config.Config, now time.Time) bool {
+	if cfg.Chart == nil || !cfg.Chart.Enabled {
+		return false
+	}
+	interval := defaultChartInterval
+	if cfg.Chart.IntervalHours > 0 {
+		interval = time.Duration(cfg.Chart.IntervalHours) * time.Hour
+	}
+	if attempt, err := time.Parse(time.RFC3339, cfg.LastChartAttempt); err == nil && now.Sub(attempt) < chartRetryInterval {
+		return false
+	}
+	last, err := time.Parse(time.RFC3339, cfg.LastChartSent)
+	if err != nil {
+		return true
+	}
+	return now.Sub(last) >= interval
+}
+
+// sendScheduledChart sends the history chart to Telegram when it is due. It
+// counts as sent once any chat received it; if none did, it is retried after
+// chartRetryInterval. Settings that can never be delivered are reported once
+// and not retried until they change. Failures never abort a check.
+func sendScheduledChart(cfg *config.Config, hostname string, now time.Time) {
+	if !chartDue(cfg, now) {
+		return
+	}
+
+	bot, err := chartChannel(cfg)
+	if err != nil {
+		if cfg.ChartSetupError != err.Error() {
+			fmt.Printf("❌ Scheduled chart cannot be sent: %v\n", err)
+			cfg.ChartSetupError = err.Error()
+			if err := cfg.Save(); err != nil {
+				fmt.Printf("⚠️  Failed to save configuration: %v\n", err)
+			}
+		}
+		return
+	}
+	cfg.ChartSetupError = ""
+
+	cfg.LastChartAttempt = now.Format(time.RFC3339)
+	delivered, err := sendChart(cfg, bot, hostname, now)
+	switch {
+	case delivered == 0:
+		fmt.Printf("⚠️  Chart not sent, retrying in %s: %v\n", chartRetryInterval, err)
+	case err != nil:
+		fmt.Printf("⚠️  Chart not sent to every chat: %v\n", err)
+	}
+	if delivered > 0 {
+		fmt.Println("✅ History chart sent.")
+		cfg.LastChartSent = cfg.LastChartAttempt
+	}
+
+	if err := cfg.Save(); err != nil {
+		fmt.Printf("⚠️  Failed to save configuration: %v\n", err)
+	}
+}
+
+// chartChannel returns the telegram channel of the scheduled chart, or an
+// error if the chart settings can never be delivered: an unknown period, a
+// missing channel, or no chat accepting the chart event
+func chartChannel(cfg *config.Config) (*notifier.TelegramNotifier, error) {
+	if _, ok := chartPeriods[cfg.Chart.Period]; !ok && cfg.Chart.Period != "" {
+		return nil, fmt.Errorf("unknown chart period %q (use week or month)", cfg.Chart.Period)
+	}
+	bot, err := telegramChannel(cfg, cfg.Chart.Channel)
+	if err != nil {
+		return nil, err
+	}
+	if !bot.AcceptsEvent(notifier.TelegramEventChart) {
+		return nil, fmt.Errorf("no chat of the telegram channel accepts the %s event", notifier.TelegramEventChart)
+	}
+	return bot, nil
+}
+
+// sendChart builds the scheduled chart and sends it through bot, returning
+// the number of chats it was delivered to
+func sendChart(cfg *config.Config, bot *notifier.TelegramNotifier, hostname string, now time.Time) (int, error) {
+	photo, caption, err := buildChart(cfg, hostname, cfg.Chart.Period, now)
+	if err != nil {
+		return 0, err
+	}
+	return bot.SendPhoto(photo, caption)
+}
@@ -0,0 +1,207 @@
+package chart
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"sort"
+	"time"
+)
+
+// Image layout in pixels
+const (
+	width      = 960
+	margin     = 16
+	laneHeight = 48
+	laneGap    = 12
+)
+
+// Families are drawn as lanes from top to bottom in this order
+var Families = []string{"ipv4", "ipv6"}
+
+// palette holds the segment colours with the square emoji they look like, so
+// that a caption can serve as the legend
+var palette = []struct {
+	Color color.RGBA
+	Emoji string
+}{
+	{color.RGBA{0xDD, 0x2E, 0x44, 0xFF}, "🟥"},
+	{color.RGBA{0x55, 0xAC, 0xEE, 0xFF}, "🟦"},
+	{color.RGBA{0x78, 0xB1, 0x59, 0xFF}, "🟩"},
+	{color.RGBA{0xF4, 0x90, 0x0C, 0xFF}, "🟧"},
+	{color.RGBA{0xAA, 0x8E, 0xD6, 0xFF}, "🟪"},
+	{color.RGBA{0xFD, 0xCB, 0x58, 0xFF}, "🟨"},
+	{color.RGBA{0xC1, 0x69, 0x4F, 0xFF}, "🟫"},
+}
+
+// Colours of everything else
+var (
+	otherColor      = color.RGBA{0x31, 0x37, 0x3D, 0xFF} // addresses beyond the palette
+	unknownColor    = color.RGBA{0xE6, 0xE6, 0xE6, 0xFF} // no history yet
+	backgroundColor = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	changeColor     = color.RGBA{0x00, 0x00, 0x00, 0xFF}
+	dayColor        = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+)
+
+// OtherEmoji stands for every address beyond the palette in a legend
+const OtherEmoji = "⬛"
+
+// Change is a recorded address change of one family
+type Change struct {
+	Family string // "ipv4" or "ipv6"
+	IP     string
+	Label  string
+	Time   time.Time
+}
+
+// Entry is a legend entry: an address held during the window and its colour
+type Entry struct {
+	Family   string
+	IP       string
+	Label    string
+	Emoji    string        // square emoji matching the segment colour
+	Duration time.Duration // time the address was held within the window
+}
+
+// Chart is a rendered timeline
+type Chart struct {
+	PNG     []byte
+	Legend  []Entry        // per family, longest held first
+	Changes map[string]int // changes per family within the window
+	Start   time.Time
+	End     time.Time
+}
+
+// segment is a period an address was held
+type segment struct {
+	ip         string
+	start, end time.Time
+}
+
+// segments returns the periods each address of a family was held between
+// start and end, and the changes within that window. Periods before the first
+// recorded change are left out.
+func segments(changes []Change, family string, start, end time.Time) ([]segment, []Change) {
+	var current *Change
+	var within []Change
+	for i := range changes {
+		c := &changes[i]
+		if c.Family != family || c.Time.After(end) {
+			continue
+		}
+		if !c.Time.After(start) {
+			current = c
+			continue
+		}
+		within = append(within, *c)
+	}
+
+	var result []segment
+	cursor := start
+	for i := range within {
+		if current != nil && current.IP != within[i].IP {
+			result = append(result, segment{ip: current.IP, start: cursor, end: within[i].Time})
+		}
+		if current == nil || current.IP != within[i].IP {
+			cursor = within[i].Time
+		}
+		current = &within[i]
+	}
+	if current != nil {
+		result = append(result, segment{ip: current.IP, start: cursor, end: end})
+	}
+	return result, within
+}
+
+// Render draws the addresses held by each family between start and end as
+// coloured lanes, with a white line at every local midnight and a black line
+// at every change.
+func Render(changes []Change, start, end time.Time) (*Chart, error) {
+	if !end.After(start) {
+		return nil, fmt.Errorf("chart window must end after it starts")
+	}
+
+	sorted := append([]Change(nil), changes...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	height := 2*margin + len(Families)*laneHeight + (len(Families)-1)*laneGap
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{backgroundColor}, image.Point{}, draw.Src)
+
+	span := end.Sub(start)
+	plotWidth := width - 2*margin
+	x := func(t time.Time) int {
+		return margin + int(float64(t.Sub(start))/float64(span)*float64(plotWidth))
+	}
+	fill := func(x0, y0, x1, y1 int, c color.Color) {
+		draw.Draw(img, image.Rect(x0, y0, x1, y1), &image.Uniform{c}, image.Point{}, draw.Src)
+	}
+
+	result := &Chart{Changes: make(map[string]int), Start: start, End: end}
+	for lane, family := range Families {
+		top := margin + lane*(laneHeight+laneGap)
+		bottom := top + laneHeight
+		fill(margin, top, width-margin, bottom, unknownColor)
+
+		segs, within := segments(sorted, family, start, end)
+		result.Changes[family] = len(within)
+
+		// Sum up the time per address and assign colours, longest first
+		labels := make(map[string]string)
+		durations := make(map[string]time.Duration)
+		for _, c := range sorted {
+			if c.Family == family {
+				labels[c.IP] = c.Label
+			}
+		}
+		var ips []string
+		for _, s := range segs {
+			if _, ok := durations[s.ip]; !ok {
+				ips = append(ips, s.ip)
+			}
+			durations[s.ip] += s.end.Sub(s.start)
+		}
+		sort.SliceStable(ips, func(i, j int) bool { return durations[ips[i]] > durations[ips[j]] })
+
+		colors := make(map[string]color.Color)
+		for i, ip := range ips {
+			entry := Entry{Family: family, IP: ip, Label: labels[ip], Duration: durations[ip]}
+			if i < len(palette) {
+				colors[ip] = palette[i].Color
+				entry.Emoji = palette[i].Emoji
+			} else {
+				colors[ip] = otherColor
+				entry.Emoji = OtherEmoji
+			}
+			result.Legend = append(result.Legend, entry)
+		}
+
+		for _, s := range segs {
+			fill(x(s.start), top, x(s.end), bottom, colors[s.ip])
+		}
+		for _, c := range within {
+			cx := x(c.Time)
+			fill(cx-1, top, cx+1, bottom, changeColor)
+		}
+	}
+
+	// Day boundaries across all lanes
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location()).AddDate(0, 0, 1)
+	for ; day.Before(end); day = day.AddDate(0, 0, 1) {
+		dx := x(day)
+		for lane := range Families {
+			top := margin + lane*(laneHeight+laneGap)
+			fill(dx, top, dx+1, top+laneHeight, dayColor)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode chart: %w", err)
+	}
+	result.PNG = buf.Bytes()
+	return result, nil
+}
@@ -0,0 +1,131 @@
+package chart
+
+import (
+	"bytes"
+	"image/png"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// at returns a time relative to a fixed day
+func at(day, hour int) time.Time {
+	return time.Date(2026, 3, 1+day, hour, 0, 0, 0, time.UTC)
+}
+
+func TestSegments(t *testing.T) {
+	start, end := at(0, 0), at(7, 0)
+
+	tests := []struct {
+		name     string
+		changes  []Change
+		segments []segment
+		within   int
+	}{
+		{
+			name:    "no history",
+			changes: nil,
+		},
+		{
+			name:     "address held since before the window",
+			changes:  []Change{{Family: "ipv4", IP: "1.1.1.1", Time: at(-3, 0)}},
+			segments: []segment{{ip: "1.1.1.1", start: start, end: end}},
+		},
+		{
+			name: "changes within the window",
+			changes: []Change{
+				{Family: "ipv4", IP: "1.1.1.1", Time: at(-3, 0)},
+				{Family: "ipv4", IP: "2.2.2.2", Time: at(2, 0)},
+				{Family: "ipv4", IP: "1.1.1.1", Time: at(5, 12)},
+			},
+			segments: []segment{
+				{ip: "1.1.1.1", start: start, end: at(2, 0)},
+				{ip: "2.2.2.2", start: at(2, 0), end: at(5, 12)},
+				{ip: "1.1.1.1", start: at(5, 12), end: end},
+			},
+			within: 2,
+		},
+		{
+			name: "period before the first change is left out",
+			changes: []Change{
+				{Family: "ipv4", IP: "2.2.2.2", Time: at(3, 0)},
+			},
+			segments: []segment{{ip: "2.2.2.2", start: at(3, 0), end: end}},
+			within:   1,
+		},
+		{
+			name: "a repeated address continues its segment",
+			changes: []Change{
+				{Family: "ipv4", IP: "1.1.1.1", Time: at(-1, 0)},
+				{Family: "ipv4", IP: "1.1.1.1", Time: at(4, 0)},
+			},
+			segments: []segment{{ip: "1.1.1.1", start: start, end: end}},
+			within:   1,
+		},
+		{
+			name: "other families and later changes are ignored",
+			changes: []Change{
+				{Family: "ipv4", IP: "1.1.1.1", Time: at(-1, 0)},
+				{Family: "ipv6", IP: "2001:db8::1", Time: at(1, 0)},
+				{Family: "ipv4", IP: "2.2.2.2", Time: at(8, 0)},
+			},
+			segments: []segment{{ip: "1.1.1.1", start: start, end: end}},
+		},
+		{
+			name: "a change exactly at the start counts as before the window",
+			changes: []Change{
+				{Family: "ipv4", IP: "1.1.1.1", Time: at(-2, 0)},
+				{Family: "ipv4", IP: "2.2.2.2", Time: start},
+			},
+			segments: []segment{{ip: "2.2.2.2", start: start, end: end}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segs, within := segments(tt.changes, "ipv4", start, end)
+			if !reflect.DeepEqual(segs, tt.segments) {
+				t.Errorf("segments = %+v, want %+v", segs, tt.segments)
+			}
+			if len(within) != tt.within {
+				t.Errorf("changes within the window = %d, want %d", len(within), tt.within)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	start, end := at(0, 0), at(7, 0)
+	changes := []Change{
+		// Unsorted on purpose, like the newest-first history
+		{Family: "ipv4", IP: "2.2.2.2", Label: "Backup LTE", Time: at(5, 0)},
+		{Family: "ipv4", IP: "1.1.1.1", Label: "Office fibre", Time: at(-3, 0)},
+		{Family: "ipv6", IP: "2001:db8::1", Time: at(1, 0)},
+	}
+
+	c, err := Render(changes, start, end)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if _, err := png.Decode(bytes.NewReader(c.PNG)); err != nil {
+		t.Errorf("chart is not a PNG: %v", err)
+	}
+	if c.Changes["ipv4"] != 1 || c.Changes["ipv6"] != 1 {
+		t.Errorf("changes = %v, want one per family", c.Changes)
+	}
+
+	want := []Entry{
+		{Family: "ipv4", IP: "1.1.1.1", Label: "Office fibre", Emoji: palette[0].Emoji, Duration: 5 * 24 * time.Hour},
+		{Family: "ipv4", IP: "2.2.2.2", Label: "Backup LTE", Emoji: palette[1].Emoji, Duration: 2 * 24 * time.Hour},
+		{Family: "ipv6", IP: "2001:db8::1", Emoji: palette[0].Emoji, Duration: 6 * 24 * time.Hour},
+	}
+	if !reflect.DeepEqual(c.Legend, want) {
+		t.Errorf("legend = %+v, want %+v", c.Legend, want)
+	}
+}
+
+func TestRenderRejectsEmptyWindow(t *testing.T) {
+	if _, err := Render(nil, at(1, 0), at(1, 0)); err == nil {
+		t.Error("Render accepted a window that does not end after it starts")
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"ip_detector/config"
+	"ip_detector/detector"
+	"ip_detector/notifier"
+)
+
+// runChangeChecks performs the optional post-change checks configured for a
+// changed address and records their results on the status
+func runChangeChecks(cfg *config.Config, family string, status *notifier.IPStatus) {
+	if !status.Changed {
+		return
+	}
+
+	if cfg.ReverseDNS != nil && cfg.ReverseDNS.Enabled {
+		checkReverseDNS(cfg.ReverseDNS, status)
+	}
+	if cfg.DNSBL != nil && cfg.DNSBL.Enabled {
+		checkDNSBL(cfg.DNSBL, status)
+	}
+	if cfg.PortChecks != nil && len(cfg.PortChecks.Ports) > 0 {
+		checkPorts(cfg, family, status)
+	}
+}
+
+// checkReverseDNS looks up and forward-confirms the PTR record of the new address
+func checkReverseDNS(rdns *config.ReverseDNSConfig, status *notifier.IPStatus) {
+	result, err := detector.LookupReverseDNS(status.Current, rdns.Resolver)
+	if err != nil {
+		fmt.Printf("⚠️  Reverse DNS lookup for %s failed: %v\n", status.Current, err)
+		if errors.Is(err, detector.ErrNoPTR) {
+			status.RDNSWarning = "No reverse DNS (PTR) record"
+		} else {
+			status.RDNSWarning = "Reverse DNS lookup failed, PTR record not verified"
+		}
+		return
+	}
+
+	status.PTR = result.Hostname
+	status.FCrDNS = result.ForwardConfirmed
+	fmt.Printf("rDNS: %s -> %s (forward-confirmed: %t)\n", status.Current, result.Hostname, result.ForwardConfirmed)
+
+	if !result.ForwardConfirmed {
+		status.RDNSWarning = "PTR hostname does not resolve back to the new address"
+	}
+
+	if rdns.ExpectedPattern != "" {
+		pattern, err := regexp.Compile(rdns.ExpectedPattern)
+		if err != nil {
+			fmt.Printf("⚠️  Invalid reverse DNS pattern %q: %v\n", rdns.ExpectedPattern, err)
+			return
+		}
+		if !pattern.MatchString(result.Hostname) {
+			mismatch := fmt.Sprintf("PTR hostname does not match expected pattern %q", rdns.ExpectedPattern)
+			if status.RDNSWarning != "" {
+				status.RDNSWarning += "; " + mismatch
+			} else {
+				status.RDNSWarning = mismatch
+			}
+		}
+	}
+}
+
+// checkDNSBL looks the new address up in the configured DNS blocklists
+func checkDNSBL(dnsbl *config.DNSBLConfig, status *notifier.IPStatus) {
+	results, err := detector.CheckDNSBL(status.Current, dnsbl.Lists, dnsbl.Resolver)
+	if err != nil {
+		fmt.Printf("⚠️  DNSBL check for %s failed: %v\n", status.Current, err)
+		return
+	}
+
+	status.DNSBLChecked = true
+	status.DNSBLLists = len(results)
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Printf("⚠️  DNSBL %s: %v\n", result.List, result.Err)
+			status.DNSBLErrors = append(status.DNSBLErrors, result.List)
+			continue
+		}
+		if result.Listed {
+			fmt.Printf("🚫 %s is listed on %s (%s)\n", status.Current, result.List, strings.Join(result.Codes, ", "))
+			status.DNSBLListed = append(status.DNSBLListed, result.List)
+		}
+	}
+
+	answered := len(results) - len(status.DNSBLErrors)
+	switch {
+	case len(status.DNSBLListed) > 0:
+	case answered == 0:
+		fmt.Printf("⚠️  DNSBL: no blocklist answered for %s\n", status.Current)
+	case len(status.DNSBLErrors) > 0:
+		fmt.Printf("⚠️  DNSBL: %s is clean on %d/%d lists\n", status.Current, answered, len(results))
+	default:
+		fmt.Printf("DNSBL: %s is clean\n", status.Current)
+	}
+}
+
+// checkPorts probes the configured ports on the current address, records the
+// results on the status and in cfg.PortState, and reports whether any port
+// changed state since the last check
+func checkPorts(cfg *config.Config, family string, status *notifier.IPStatus) bool {
+	pc := cfg.PortChecks
+
+	timeout := 5 * time.Second
+	if pc.TimeoutSeconds > 0 {
+		timeout = time.Duration(pc.TimeoutSeconds) * time.Second
+	}
+
+	probes := make([]detector.PortProbe, 0, len(pc.Ports))
+	for _, port := range pc.Ports {
+		probes = append(probes, detector.PortProbe{
+			Name:     port.Name,
+			Protocol: strings.ToLower(port.Protocol),
+			Port:     port.Port,
+		})
+	}
+
+	if cfg.PortState == nil {
+		cfg.PortState = make(map[string]string)
+	}
+
+	changed := false
+	status.Ports = nil
+	for _, result := range detector.CheckPorts(status.Current, probes, pc.CheckerURL, timeout) {
+		port := notifier.PortStatus{
+			Service:      result.String(),
+			Reachable:    result.Reachable,
+			Inconclusive: result.Inconclusive,
+		}
+
+		if result.Err != nil {
+			fmt.Printf("⚠️  Port check %s on %s failed: %v\n", result.String(), status.Current, result.Err)
+			port.Inconclusive = true
+			status.Ports = append(status.Ports, port)
+			continue
+		}
+
+		state := "closed"
+		switch {
+		case result.Inconclusive:
+			state = "unknown"
+		case result.Reachable:
+			state = "open"
+		}
+		fmt.Printf("Port %s on %s: %s\n", result.String(), status.Current, state)
+
+		key := fmt.Sprintf("%s %s/%d", family, result.Protocol, result.Port)
+		if previous, ok := cfg.PortState[key]; ok && previous != state {
+			changed = true
+		}
+		cfg.PortState[key] = state
+		status.Ports = append(status.Ports, port)
+	}
+
+	return changed
+}
+
+// portCheckDue reports whether the periodic port check interval has elapsed
+func portCheckDue(cfg *config.Config, now time.Time) bool {
+	pc := cfg.PortChecks
+	if pc == nil || pc.IntervalMinutes <= 0 || len(pc.Ports) == 0 {
+		return false
+	}
+
+	last, err := time.Parse(time.RFC3339, cfg.LastPortCheck)
+	if err != nil {
+		return true
+	}
+	return now.Sub(last) >= time.Duration(pc.IntervalMinutes)*time.Minute
+}
+
+// runPeriodicPortCheck re-checks the ports on unchanged addresses and sends a
+// notification if any port changed state
+func runPeriodicPortCheck(cfg *config.Config, hostname string, ipv4Status, ipv6Status notifier.IPStatus, now time.Time) error {
+	changed := false
+	if ipv4Status.Current != "" && checkPorts(cfg, "ipv4", &ipv4Status) {
+		changed = true
+	}
+	if ipv6Status.Current != "" && checkPorts(cfg, "ipv6", &ipv6Status) {
+		changed = true
+	}
+
+	cfg.LastPortCheck = now.Format(time.RFC3339)
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	if !changed {
+		return nil
+	}
+
+	event := notifier.ChangeEvent{
+		Hostname:     hostname,
+		IPv4:         ipv4Status,
+		IPv6:         ipv6Status,
+		Timestamp:    now,
+		PortsChanged: true,
+	}
+	if notificationsMuted(cfg, now) {
+		return nil
+	}
+	if err := notifyAll(cfg, func(n notifier.Notifier) error {
+		return n.SendChange(event)
+	}); err != nil {
+		return err
+	}
+
+	fmt.Println("✅ Port reachability notification sent.")
+	return nil
+}
@@ -25,16 +25,180 @@ type Config struct {
 	LastKnownIPv4     string `json:"last_known_ipv4"`
 	LastKnownIPv6     string `json:"last_known_ipv6"`
 	LastChecked       string `json:"last_checked"`
+	// Telegram message formatting: "HTML" (default), "MarkdownV2" or "Markdown"
+	TelegramParseMode string `json:"telegram_parse_mode,omitempty"`
+	// Telegram Bot API base URL, e.g. a self-hosted telegram-bot-api server
+	TelegramAPIURL string `json:"telegram_api_url,omitempty"`
+	// Additional detection services, e.g. an internal echo service
+	CustomServices []CustomService `json:"custom_services,omitempty"`
+	// TLS settings per detection service name
+	ServiceTLS map[string]TLSSettings `json:"service_tls,omitempty"`
+	// Named addresses/networks used to describe changes (e.g. "Office fibre")
+	KnownIPs []KnownIP `json:"known_ips,omitempty"`
+	// Reverse DNS lookup and forward confirmation of new addresses
+	ReverseDNS *ReverseDNSConfig `json:"reverse_dns,omitempty"`
+	// DNS blocklist lookup of new addresses
+	DNSBL *DNSBLConfig `json:"dnsbl,omitempty"`
+	// Inbound port reachability checks on the public addresses
+	PortChecks *PortCheckConfig `json:"port_checks,omitempty"`
+	// Last port reachability check time and result per "family proto/port"
+	LastPortCheck string            `json:"last_port_check,omitempty"`
+	PortState     map[string]string `json:"port_state,omitempty"`
+	// MQTT publishing of the current state on every check
+	MQTT *MQTTConfig `json:"mqtt,omitempty"`
+	// Time of the last detected address change
+	LastChanged string `json:"last_changed,omitempty"`
+	// Local commands run on changes (and optionally failures/recoveries)
+	Hooks           []HookConfig `json:"hooks,omitempty"`
+	HookConcurrency int          `json:"hook_concurrency,omitempty"` // default 1
+	// Config files rendered from templates whenever the addresses change
+	Templates []TemplateConfig `json:"templates,omitempty"`
+	// Notification channels in addition to the Telegram credentials above
+	Notifiers []NotifierConfig `json:"notifiers,omitempty"`
+	// Interactive Telegram bot commands answered in daemon mode
+	TelegramBot *TelegramBotConfig `json:"telegram_bot,omitempty"`
+	// History chart sent to Telegram on a schedule, when it was last sent,
+	// when sending it was last attempted, and the last problem with its
+	// settings, so that it is reported only once
+	Chart            *ChartConfig `json:"chart,omitempty"`
+	LastChartSent    string       `json:"last_chart_sent,omitempty"`
+	LastChartAttempt string       `json:"last_chart_attempt,omitempty"`
+	ChartSetupError  string       `json:"chart_setup_error,omitempty"`
+	// IDs of live status messages per notifier channel and destination chat
+	StatusMessages map[string]map[string]int64 `json:"status_messages,omitempty"`
+	// Event notifications are suppressed until this time (set by /mute)
+	MutedUntil string `json:"muted_until,omitempty"`
+	// Set while IP detection is failing, so failures are reported only once
+	DetectionFailing bool `json:"detection_failing,omitempty"`
+	// Set until the notification for the last DetectionFailing change was
+	// delivered through at least one channel
+	DetectionAlertPending bool `json:"detection_alert_pending,omitempty"`
 	// Legacy field for backward compatibility (will be migrated to LastKnownIPv4)
 	LastKnownIP string `json:"last_known_ip,omitempty"`
 }
 
+// CustomService is a user-defined IP detection service
+type CustomService struct {
+	Name    string `json:"name"`
+	IPv4URL string `json:"ipv4_url"`
+	IPv6URL string `json:"ipv6_url,omitempty"`
+}
+
+// TLSSettings customises the TLS connection to a detection service
+type TLSSettings struct {
+	CAFile     string   `json:"ca_file,omitempty"`     // PEM bundle of additional trusted CAs
+	CertFile   string   `json:"cert_file,omitempty"`   // client certificate for mTLS
+	KeyFile    string   `json:"key_file,omitempty"`    // client key for mTLS
+	MinVersion string   `json:"min_version,omitempty"` // "1.2" or "1.3"
+	Pins       []string `json:"pins,omitempty"`        // base64 SHA-256 SPKI hashes ("sha256/..." accepted)
+	ServerName string   `json:"server_name,omitempty"` // SNI override
+}
+
+// ReverseDNSConfig controls the PTR/FCrDNS check performed after a change
+type ReverseDNSConfig struct {
+	Enabled         bool   `json:"enabled"`
+	ExpectedPattern string `json:"expected_pattern,omitempty"` // regular expression the PTR hostname must match
+	Resolver        string `json:"resolver,omitempty"`         // DNS server to query, e.g. "127.0.0.1:5353"
+}
+
+// DNSBLConfig controls the DNS blocklist check performed after a change
+type DNSBLConfig struct {
+	Enabled  bool     `json:"enabled"`
+	Lists    []string `json:"lists,omitempty"`    // blocklist zones; defaults to detector.DefaultDNSBLs
+	Resolver string   `json:"resolver,omitempty"` // DNS server to query, e.g. "127.0.0.1:5353"
+}
+
+// PortCheckConfig lists the ports that should be reachable on the public IP
+type PortCheckConfig struct {
+	Ports []PortSpec `json:"ports"`
+	// External checker URL with {ip}, {port} and {proto} placeholders;
+	// if empty, ports are dialled directly (requires hairpin NAT)
+	CheckerURL      string `json:"checker_url,omitempty"`
+	IntervalMinutes int    `json:"interval_minutes,omitempty"` // periodic re-check, 0 = only after changes
+	TimeoutSeconds  int    `json:"timeout_seconds,omitempty"`  // per-port timeout, default 5
+}
+
+// PortSpec is a single service port to check
+type PortSpec struct {
+	Name     string `json:"name,omitempty"`
+	Protocol string `json:"protocol"` // "tcp" or "udp"
+	Port     int    `json:"port"`
+}
+
+// MQTTConfig controls publishing the current state to an MQTT broker
+type MQTTConfig struct {
+	Enabled         bool         `json:"enabled"`
+	Broker          string       `json:"broker"`                     // "tcp://host:1883" or "ssl://host:8883"
+	ProtocolVersion string       `json:"protocol_version,omitempty"` // "3.1.1" (default) or "5"
+	ClientID        string       `json:"client_id,omitempty"`
+	Username        string       `json:"username,omitempty"`
+	Password        string       `json:"password,omitempty"` // may be encrypted ("enc:...")
+	QoS             int          `json:"qos,omitempty"`      // 0 or 1
+	TopicPrefix     string       `json:"topic_prefix,omitempty"`
+	Discovery       bool         `json:"discovery,omitempty"` // Home Assistant MQTT discovery
+	DiscoveryPrefix string       `json:"discovery_prefix,omitempty"`
+	TLS             *TLSSettings `json:"tls,omitempty"`
+}
+
+// TelegramBotConfig enables answering commands sent to the Telegram bot
+type TelegramBotConfig struct {
+	Enabled bool   `json:"enabled"`
+	Channel string `json:"channel,omitempty"` // telegram notifier to use, default "telegram"
+}
+
+// ChartConfig schedules the IP history chart
+type ChartConfig struct {
+	Enabled       bool   `json:"enabled"`
+	Period        string `json:"period,omitempty"`         // "week" (default) or "month"
+	IntervalHours int    `json:"interval_hours,omitempty"` // default 168 (weekly)
+	Channel       string `json:"channel,omitempty"`        // telegram notifier to use, default "telegram"
+}
+
+// HookConfig configures a local command run on events
+type HookConfig struct {
+	Name           string   `json:"name"`
+	Command        string   `json:"command"`                   // executed with /bin/sh -c
+	Events         []string `json:"events,omitempty"`          // "change" (default), "failure", "recovery"
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"` // default 30
+}
+
+// TemplateConfig configures a template rendered to a destination file
+type TemplateConfig struct {
+	Source        string `json:"source"`                   // Go text/template file
+	Destination   string `json:"destination"`              // rendered file, replaced atomically
+	Mode          string `json:"mode,omitempty"`           // octal permissions, default "0644"
+	ReloadCommand string `json:"reload_command,omitempty"` // run after the destination changed
+}
+
+// HookRecord is the outcome of a hook run stored in the history
+type HookRecord struct {
+	Name       string `json:"name"`
+	ExitCode   int    `json:"exit_code"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
 // IPHistoryEntry represents a single IP change record
 type IPHistoryEntry struct {
 	Timestamp string `json:"timestamp"`
 	Type      string `json:"type"` // "ipv4" or "ipv6"
 	OldIP     string `json:"old_ip"`
 	NewIP     string `json:"new_ip"`
+	OldLabel  string `json:"old_label,omitempty"`
+	NewLabel  string `json:"new_label,omitempty"`
+	Event     string `json:"event,omitempty"` // "failover", "failback" or empty
+	PTR       string `json:"ptr,omitempty"`
+	FCrDNS    bool   `json:"fcrdns,omitempty"`
+	// "clean", "listed", "incomplete" (clean on the lists that answered) or
+	// "unavailable" (no list answered) when the DNSBL check ran
+	DNSBLStatus string   `json:"dnsbl_status,omitempty"`
+	DNSBLListed []string `json:"dnsbl_listed,omitempty"`
+	DNSBLErrors []string `json:"dnsbl_errors,omitempty"` // lists that could not be queried
+	// Reachable and unreachable ports on the new address, e.g. "ssh (tcp/22)"
+	PortsReachable   []string `json:"ports_reachable,omitempty"`
+	PortsUnreachable []string `json:"ports_unreachable,omitempty"`
+	// Hooks run for this change
+	Hooks []HookRecord `json:"hooks,omitempty"`
 }
 
 // getConfigDir returns the path to the config directory
@@ -99,6 +263,11 @@ func Load() (*Config, error) {
 		_ = config.Save()
 	}
 
+	// Encrypt secrets that were entered in plain text
+	if sealed, err := config.sealSecrets(); err == nil && sealed {
+		_ = config.Save()
+	}
+
 	return &config, nil
 }
 
@@ -121,6 +290,10 @@ func (c *Config) Save() error {
 	// Clear legacy field
 	c.LastKnownIP = ""
 
+	if _, err := c.sealSecrets(); err != nil {
+		return err
+	}
+
 	data, err := json.MarshalIndent(c, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to serialize config: %w", err)
@@ -213,16 +386,23 @@ func SaveHistory(history []IPHistoryEntry) error {
 
 // AddHistoryEntry adds a new entry to the IP history
 func AddHistoryEntry(ipType, oldIP, newIP string) error {
+	return AddHistoryRecord(IPHistoryEntry{
+		Type:  ipType,
+		OldIP: oldIP,
+		NewIP: newIP,
+	})
+}
+
+// AddHistoryRecord adds a fully populated entry to the IP history,
+// stamping it with the current time if no timestamp is set
+func AddHistoryRecord(entry IPHistoryEntry) error {
 	history, err := LoadHistory()
 	if err != nil {
 		return err
 	}
 
-	entry := IPHistoryEntry{
-		Timestamp: time.Now().Format(time.RFC3339),
-		Type:      ipType,
-		OldIP:     oldIP,
-		NewIP:     newIP,
+	if entry.Timestamp == "" {
+		entry.Timestamp = time.Now().Format(time.RFC3339)
 	}
 
 	// Prepend new entry
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// TransitionFailover marks a move away from a primary link
+	TransitionFailover = "failover"
+	// TransitionFailback marks a return to a primary link
+	TransitionFailback = "failback"
+)
+
+// KnownIP assigns a human-readable label to an address or network
+type KnownIP struct {
+	Label   string `json:"label"`
+	CIDR    string `json:"cidr"`              // single address ("203.0.113.7") or network ("198.51.100.0/24")
+	Primary bool   `json:"primary,omitempty"` // moving away from a primary link is reported as a failover
+}
+
+// LabelDuration holds the total time an address family spent on one link
+type LabelDuration struct {
+	Label    string
+	Duration time.Duration
+}
+
+// network parses the CIDR field, accepting bare addresses as host routes
+func (k KnownIP) network() *net.IPNet {
+	if !strings.Contains(k.CIDR, "/") {
+		ip := net.ParseIP(strings.TrimSpace(k.CIDR))
+		if ip == nil {
+			return nil
+		}
+		bits := 128
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	}
+
+	_, network, err := net.ParseCIDR(strings.TrimSpace(k.CIDR))
+	if err != nil {
+		return nil
+	}
+	return network
+}
+
+// LookupKnownIP returns the most specific catalogue entry containing ip, or nil
+func (c *Config) LookupKnownIP(ip string) *KnownIP {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil
+	}
+
+	var best *KnownIP
+	bestBits := -1
+	for i := range c.KnownIPs {
+		network := c.KnownIPs[i].network()
+		if network == nil || !network.Contains(parsed) {
+			continue
+		}
+		if bits, _ := network.Mask.Size(); bits > bestBits {
+			best = &c.KnownIPs[i]
+			bestBits = bits
+		}
+	}
+	return best
+}
+
+// LabelFor returns the catalogue label for ip, or an empty string if unknown
+func (c *Config) LabelFor(ip string) string {
+	if known := c.LookupKnownIP(ip); known != nil {
+		return known.Label
+	}
+	return ""
+}
+
+// ClassifyTransition reports whether a change from oldIP to newIP is a
+// failover, a failback, or neither (empty string)
+func (c *Config) ClassifyTransition(oldIP, newIP string) string {
+	if oldIP == "" || newIP == "" {
+		return ""
+	}
+
+	oldKnown := c.LookupKnownIP(oldIP)
+	newKnown := c.LookupKnownIP(newIP)
+	oldPrimary := oldKnown != nil && oldKnown.Primary
+	newPrimary := newKnown != nil && newKnown.Primary
+
+	switch {
+	case oldPrimary && !newPrimary:
+		return TransitionFailover
+	case !oldPrimary && newPrimary:
+		return TransitionFailback
+	}
+	return ""
+}
+
+// LabelStats totals how long the given address family spent on each link,
+// based on the change history. Addresses are labelled with the current
+// catalogue so that entries recorded before a label existed are still grouped;
+// unlabelled addresses are reported by their raw value.
+func (c *Config) LabelStats(history []IPHistoryEntry, ipType string, now time.Time) []LabelDuration {
+	// History is stored newest first; walk it oldest first
+	var entries []IPHistoryEntry
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Type == ipType {
+			entries = append(entries, history[i])
+		}
+	}
+
+	totals := make(map[string]time.Duration)
+	for i, entry := range entries {
+		start, err := time.Parse(time.RFC3339, entry.Timestamp)
+		if err != nil {
+			continue
+		}
+
+		end := now
+		if i+1 < len(entries) {
+			if next, err := time.Parse(time.RFC3339, entries[i+1].Timestamp); err == nil {
+				end = next
+			}
+		}
+		if end.Before(start) {
+			continue
+		}
+
+		label := c.LabelFor(entry.NewIP)
+		if label == "" {
+			label = entry.NewIP
+		}
+		totals[label] += end.Sub(start)
+	}
+
+	stats := make([]LabelDuration, 0, len(totals))
+	for label, duration := range totals {
+		stats = append(stats, LabelDuration{Label: label, Duration: duration})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Duration == stats[j].Duration {
+			return stats[i].Label < stats[j].Label
+		}
+		return stats[i].Duration > stats[j].Duration
+	})
+	return stats
+}
@@ -0,0 +1,277 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"ip_detector/storage"
+)
+
+// secretPrefix marks a string value in the configuration as encrypted
+const secretPrefix = "enc:"
+
+// NotifyURLsEnv lists notification URLs, separated by whitespace, that are
+// used in addition to the configured channels
+const NotifyURLsEnv = "IPD_NOTIFY_URLS"
+
+// NotifierConfig configures one notification channel
+type NotifierConfig struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"` // registered notifier type, e.g. "telegram"
+	Enabled bool   `json:"enabled"`
+	// Type-specific options; any string value prefixed with "enc:" is
+	// decrypted with storage.Decrypt before it is passed to the notifier
+	Options json.RawMessage `json:"options,omitempty"`
+	// Notification URL such as "telegram://TOKEN@telegram?chats=42", used
+	// instead of type and options; may be encrypted with the "enc:" prefix
+	URL string `json:"url,omitempty"`
+}
+
+// EncryptSecret encrypts a value for use in notifier options
+func EncryptSecret(plaintext string) (string, error) {
+	encrypted, err := storage.Encrypt(plaintext)
+	if err != nil {
+		return "", err
+	}
+	return secretPrefix + encrypted, nil
+}
+
+// RevealSecret decrypts a value produced by EncryptSecret; values without
+// the "enc:" prefix are returned unchanged
+func RevealSecret(value string) (string, error) {
+	if !strings.HasPrefix(value, secretPrefix) {
+		return value, nil
+	}
+	return storage.Decrypt(strings.TrimPrefix(value, secretPrefix))
+}
+
+// DecryptedOptions returns the notifier options with every encrypted string
+// value replaced by its plaintext
+func (n NotifierConfig) DecryptedOptions() (json.RawMessage, error) {
+	if len(n.Options) == 0 {
+		return n.Options, nil
+	}
+
+	var options interface{}
+	if err := json.Unmarshal(n.Options, &options); err != nil {
+		return nil, fmt.Errorf("failed to parse options: %w", err)
+	}
+
+	options, err := revealSecrets(options)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize options: %w", err)
+	}
+	return data, nil
+}
+
+// revealSecrets walks a decoded JSON value and decrypts "enc:" strings
+func revealSecrets(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		plaintext, err := RevealSecret(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt secret: %w", err)
+		}
+		return plaintext, nil
+	case map[string]interface{}:
+		for key, item := range v {
+			revealed, err := revealSecrets(item)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			v[key] = revealed
+		}
+	case []interface{}:
+		for i, item := range v {
+			revealed, err := revealSecrets(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = revealed
+		}
+	}
+	return value, nil
+}
+
+// sealedOptions lists, per notifier type, the option values that are always
+// stored encrypted. A path walks object keys, and "*" every array element.
+var sealedOptions = map[string][][]string{
+	"telegram": {{"bot_token"}, {"chat_id"}, {"chats", "*", "chat_id"}},
+	"email":    {{"password"}},
+}
+
+// sealedURLSchemes lists the notification URL schemes that are always stored
+// encrypted, as the URL carries the same secrets as the options
+var sealedURLSchemes = map[string]bool{
+	"telegram": true,
+	"smtp":     true,
+	"smtps":    true,
+}
+
+// sealSecrets encrypts the plaintext secrets of the configured channels, so
+// that values entered by hand are not kept on disk in plain text. It reports
+// whether anything was encrypted.
+func (c *Config) sealSecrets() (bool, error) {
+	sealed := false
+	for i := range c.Notifiers {
+		nc := &c.Notifiers[i]
+
+		if nc.URL != "" && !strings.HasPrefix(nc.URL, secretPrefix) && sealedURLSchemes[urlSchemeName(nc.URL)] {
+			encrypted, err := EncryptSecret(nc.URL)
+			if err != nil {
+				return false, fmt.Errorf("failed to encrypt url of channel %s: %w", nc.Name, err)
+			}
+			nc.URL = encrypted
+			sealed = true
+		}
+
+		paths := sealedOptions[nc.Type]
+		if len(paths) == 0 || len(nc.Options) == 0 {
+			continue
+		}
+		var options interface{}
+		if err := json.Unmarshal(nc.Options, &options); err != nil {
+			return false, fmt.Errorf("failed to parse options of channel %s: %w", nc.Name, err)
+		}
+		changed := false
+		for _, path := range paths {
+			done, err := sealPath(options, path)
+			if err != nil {
+				return false, fmt.Errorf("failed to encrypt %s of channel %s: %w", strings.Join(path, "."), nc.Name, err)
+			}
+			changed = changed || done
+		}
+		if !changed {
+			continue
+		}
+		data, err := json.Marshal(options)
+		if err != nil {
+			return false, fmt.Errorf("failed to serialize options of channel %s: %w", nc.Name, err)
+		}
+		nc.Options = data
+		sealed = true
+	}
+	return sealed, nil
+}
+
+// sealPath encrypts the non-empty plaintext strings found at path in a
+// decoded JSON value and reports whether any was encrypted
+func sealPath(value interface{}, path []string) (bool, error) {
+	if len(path) == 0 {
+		return false, nil
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		item, ok := v[path[0]]
+		if !ok {
+			return false, nil
+		}
+		if plaintext, ok := item.(string); ok && len(path) == 1 {
+			if plaintext == "" || strings.HasPrefix(plaintext, secretPrefix) {
+				return false, nil
+			}
+			encrypted, err := EncryptSecret(plaintext)
+			if err != nil {
+				return false, err
+			}
+			v[path[0]] = encrypted
+			return true, nil
+		}
+		return sealPath(item, path[1:])
+	case []interface{}:
+		if path[0] != "*" {
+			return false, nil
+		}
+		sealed := false
+		for _, item := range v {
+			done, err := sealPath(item, path[1:])
+			if err != nil {
+				return false, err
+			}
+			sealed = sealed || done
+		}
+		return sealed, nil
+	}
+	return false, nil
+}
+
+// NotifierConfigs returns all configured notification channels. Telegram
+// credentials stored by the setup wizard are exposed as a channel named
+// "telegram" unless a channel with that name is configured explicitly, and
+// every URL in IPD_NOTIFY_URLS is added as a channel named after its scheme.
+func (c *Config) NotifierConfigs() []NotifierConfig {
+	configs := make([]NotifierConfig, 0, len(c.Notifiers)+1)
+
+	if c.EncryptedBotToken != "" && c.findNotifier("telegram") == nil {
+		options, _ := json.Marshal(map[string]string{
+			"bot_token":  secretPrefix + c.EncryptedBotToken,
+			"chat_id":    secretPrefix + c.EncryptedChatID,
+			"parse_mode": c.TelegramParseMode,
+			"api_url":    c.TelegramAPIURL,
+		})
+		configs = append(configs, NotifierConfig{
+			Name:    "telegram",
+			Type:    "telegram",
+			Enabled: true,
+			Options: options,
+		})
+	}
+
+	configs = append(configs, c.Notifiers...)
+
+	for _, rawURL := range EnvNotifyURLs() {
+		configs = append(configs, NotifierConfig{
+			Name:    uniqueChannelName(configs, urlSchemeName(rawURL)),
+			Enabled: true,
+			URL:     rawURL,
+		})
+	}
+	return configs
+}
+
+// EnvNotifyURLs returns the notification URLs from IPD_NOTIFY_URLS
+func EnvNotifyURLs() []string {
+	return strings.Fields(os.Getenv(NotifyURLsEnv))
+}
+
+// urlSchemeName returns the service part of a notification URL's scheme,
+// e.g. "generic" for "generic+https://..."
+func urlSchemeName(rawURL string) string {
+	name, _, found := strings.Cut(rawURL, "://")
+	if !found || name == "" {
+		return "url"
+	}
+	name, _, _ = strings.Cut(name, "+")
+	return strings.ToLower(name)
+}
+
+// uniqueChannelName returns name, with a "-2", "-3", ... suffix if a channel
+// of that name already exists
+func uniqueChannelName(configs []NotifierConfig, name string) string {
+	taken := make(map[string]bool, len(configs))
+	for _, nc := range configs {
+		taken[nc.Name] = true
+	}
+	candidate := name
+	for i := 2; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s-%d", name, i)
+	}
+	return candidate
+}
+
+// findNotifier returns the explicitly configured channel with the given name
+func (c *Config) findNotifier(name string) *NotifierConfig {
+	for i := range c.Notifiers {
+		if c.Notifiers[i].Name == name {
+			return &c.Notifiers[i]
+		}
+	}
+	return nil
+}
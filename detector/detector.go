@@ -1,6 +1,8 @@
 package detector
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,6 +15,7 @@ type Service struct {
 	Name    string
 	IPv4URL string
 	IPv6URL string
+	TLS     *tls.Config // optional custom TLS settings; nil uses the defaults
 }
 
 // Available IP detection services with dedicated IPv4/IPv6 endpoints
@@ -39,11 +42,39 @@ func GetServiceByName(name string) *Service {
 	return nil
 }
 
+// RegisterService adds a detection service, replacing any existing service
+// with the same name
+func RegisterService(service Service) {
+	for i := range Services {
+		if Services[i].Name == service.Name {
+			Services[i] = service
+			return
+		}
+	}
+	Services = append(Services, service)
+}
+
+// SetServiceTLS sets the TLS configuration used for the named service
+func SetServiceTLS(name string, tlsConfig *tls.Config) error {
+	for i := range Services {
+		if Services[i].Name == name {
+			Services[i].TLS = tlsConfig
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown service %q", name)
+}
+
 // fetchIP makes an HTTP request and returns the IP address
-func fetchIP(url string) (string, error) {
+func fetchIP(url string, tlsConfig *tls.Config) (string, error) {
 	client := &http.Client{
 		Timeout: 10 * time.Second,
 	}
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		client.Transport = transport
+	}
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -73,20 +104,25 @@ func fetchIP(url string) (string, error) {
 
 // DetectIPv4 fetches the public IPv4 address from the specified service
 func DetectIPv4(service *Service) (string, error) {
-	return fetchIP(service.IPv4URL)
+	return fetchIP(service.IPv4URL, service.TLS)
 }
 
 // DetectIPv6 fetches the public IPv6 address from the specified service
 func DetectIPv6(service *Service) (string, error) {
-	return fetchIP(service.IPv6URL)
+	return fetchIP(service.IPv6URL, service.TLS)
 }
 
-// DetectIPv4WithFallback tries the primary service first, then falls back to others
+// DetectIPv4WithFallback tries the primary service first, then falls back to
+// others. A failed certificate pin check is returned as an error right away,
+// without trying other services, as it indicates an intercepted connection.
 func DetectIPv4WithFallback(primaryService string) (string, string, error) {
 	// Try primary service first
 	primary := GetServiceByName(primaryService)
 	if primary != nil {
 		ip, err := DetectIPv4(primary)
+		if errors.Is(err, ErrPinMismatch) {
+			return "", "", fmt.Errorf("%s: %w", primary.Name, err)
+		}
 		if err == nil && ip != "" {
 			return ip, primary.Name, nil
 		}
@@ -98,6 +134,9 @@ func DetectIPv4WithFallback(primaryService string) (string, string, error) {
 			continue
 		}
 		ip, err := DetectIPv4(&service)
+		if errors.Is(err, ErrPinMismatch) {
+			return "", "", fmt.Errorf("%s: %w", service.Name, err)
+		}
 		if err == nil && ip != "" {
 			return ip, service.Name, nil
 		}
@@ -107,12 +146,16 @@ func DetectIPv4WithFallback(primaryService string) (string, string, error) {
 }
 
 // DetectIPv6WithFallback tries the primary service first, then falls back to others
-// Returns empty string without error if IPv6 is not available
+// Returns empty string without error if IPv6 is not available; a failed
+// certificate pin check is returned as an error like for IPv4
 func DetectIPv6WithFallback(primaryService string) (string, string, error) {
 	// Try primary service first
 	primary := GetServiceByName(primaryService)
 	if primary != nil {
 		ip, err := DetectIPv6(primary)
+		if errors.Is(err, ErrPinMismatch) {
+			return "", "", fmt.Errorf("%s: %w", primary.Name, err)
+		}
 		if err == nil && ip != "" {
 			return ip, primary.Name, nil
 		}
@@ -124,6 +167,9 @@ func DetectIPv6WithFallback(primaryService string) (string, string, error) {
 			continue
 		}
 		ip, err := DetectIPv6(&service)
+		if errors.Is(err, ErrPinMismatch) {
+			return "", "", fmt.Errorf("%s: %w", service.Name, err)
+		}
 		if err == nil && ip != "" {
 			return ip, service.Name, nil
 		}
@@ -0,0 +1,102 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// DefaultDNSBLs are queried when no blocklists are configured
+var DefaultDNSBLs = []string{
+	"zen.spamhaus.org",
+	"b.barracudacentral.org",
+	"bl.spamcop.net",
+}
+
+// DNSBLResult holds the outcome of looking an address up in one blocklist
+type DNSBLResult struct {
+	List   string
+	Listed bool
+	Codes  []string // return codes, e.g. "127.0.0.2"
+	Err    error
+}
+
+// reverseName builds the DNSBL query prefix for ip: reversed octets for IPv4
+// and reversed nibbles for IPv6
+func reverseName(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d", v4[3], v4[2], v4[1], v4[0])
+	}
+
+	const hexDigits = "0123456789abcdef"
+	v6 := ip.To16()
+	nibbles := make([]string, 0, 32)
+	for i := len(v6) - 1; i >= 0; i-- {
+		nibbles = append(nibbles, string(hexDigits[v6[i]&0x0f]), string(hexDigits[v6[i]>>4]))
+	}
+	return strings.Join(nibbles, ".")
+}
+
+// CheckDNSBL looks ip up in each of the given blocklists concurrently.
+// Results are returned in the same order as lists. An empty resolver uses
+// the system configuration.
+func CheckDNSBL(ip string, lists []string, resolver string) ([]DNSBLResult, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil, fmt.Errorf("invalid IP address: %s", ip)
+	}
+	if len(lists) == 0 {
+		lists = DefaultDNSBLs
+	}
+
+	r := newResolver(resolver)
+	prefix := reverseName(parsed)
+	results := make([]DNSBLResult, len(lists))
+
+	var wg sync.WaitGroup
+	for i, list := range lists {
+		wg.Add(1)
+		go func(i int, list string) {
+			defer wg.Done()
+			results[i] = queryDNSBL(r, prefix, list)
+		}(i, list)
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// queryDNSBL performs a single blocklist lookup
+func queryDNSBL(r *net.Resolver, prefix, list string) DNSBLResult {
+	result := DNSBLResult{List: list}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dnsTimeout)
+	defer cancel()
+
+	addrs, err := r.LookupHost(ctx, prefix+"."+list)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return result // NXDOMAIN means not listed
+		}
+		result.Err = fmt.Errorf("lookup failed: %w", err)
+		return result
+	}
+
+	for _, addr := range addrs {
+		// Spamhaus answers 127.255.255.x when refusing a query (e.g. via a
+		// public resolver); that is an error, not a listing
+		if strings.HasPrefix(addr, "127.255.255.") {
+			result.Err = fmt.Errorf("query refused by blocklist (code %s)", addr)
+			return result
+		}
+		if strings.HasPrefix(addr, "127.") {
+			result.Listed = true
+			result.Codes = append(result.Codes, addr)
+		}
+	}
+	return result
+}
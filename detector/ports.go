@@ -0,0 +1,166 @@
+package detector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// PortProbe describes a service port that should be reachable on the public IP
+type PortProbe struct {
+	Name     string
+	Protocol string // "tcp" or "udp"
+	Port     int
+}
+
+// PortResult holds the outcome of probing a single port
+type PortResult struct {
+	PortProbe
+	Reachable    bool
+	Inconclusive bool // e.g. UDP without a reply: open or filtered
+	Err          error
+}
+
+// String formats the probe as "name (tcp/22)"
+func (p PortProbe) String() string {
+	if p.Name == "" {
+		return fmt.Sprintf("%s/%d", p.Protocol, p.Port)
+	}
+	return fmt.Sprintf("%s (%s/%d)", p.Name, p.Protocol, p.Port)
+}
+
+// CheckPorts probes every port on ip concurrently. If checkerURL is set the
+// external checker is asked; otherwise the port is dialled directly, which
+// relies on the router supporting hairpin NAT. Results keep the probe order.
+func CheckPorts(ip string, probes []PortProbe, checkerURL string, timeout time.Duration) []PortResult {
+	results := make([]PortResult, len(probes))
+
+	var wg sync.WaitGroup
+	for i, probe := range probes {
+		wg.Add(1)
+		go func(i int, probe PortProbe) {
+			defer wg.Done()
+			if checkerURL != "" {
+				results[i] = checkPortExternal(ip, probe, checkerURL, timeout)
+			} else {
+				results[i] = checkPortHairpin(ip, probe, timeout)
+			}
+		}(i, probe)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// checkPortExternal asks an external checker whether ip:port is reachable.
+// The URL may contain {ip}, {port} and {proto} placeholders; the checker must
+// answer 200 with either {"reachable": bool} or a plain "open"/"closed" body.
+func checkPortExternal(ip string, probe PortProbe, checkerURL string, timeout time.Duration) PortResult {
+	result := PortResult{PortProbe: probe}
+
+	target := strings.NewReplacer(
+		"{ip}", url.QueryEscape(ip),
+		"{port}", strconv.Itoa(probe.Port),
+		"{proto}", probe.Protocol,
+	).Replace(checkerURL)
+
+	client := &http.Client{Timeout: timeout}
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to create request: %w", err)
+		return result
+	}
+	req.Header.Set("User-Agent", "ip_detector/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Err = fmt.Errorf("checker request failed: %w", err)
+		return result
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		result.Err = fmt.Errorf("checker returned status code %d", resp.StatusCode)
+		return result
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		result.Err = fmt.Errorf("failed to read checker response: %w", err)
+		return result
+	}
+
+	var parsed struct {
+		Reachable *bool `json:"reachable"`
+	}
+	if json.Unmarshal(body, &parsed) == nil && parsed.Reachable != nil {
+		result.Reachable = *parsed.Reachable
+		return result
+	}
+
+	switch strings.ToLower(strings.TrimSpace(string(body))) {
+	case "open", "reachable", "true", "1":
+		result.Reachable = true
+	case "closed", "unreachable", "false", "0":
+		result.Reachable = false
+	default:
+		result.Err = fmt.Errorf("unrecognised checker response: %q", strings.TrimSpace(string(body)))
+	}
+	return result
+}
+
+// checkPortHairpin dials ip:port from this host
+func checkPortHairpin(ip string, probe PortProbe, timeout time.Duration) PortResult {
+	result := PortResult{PortProbe: probe}
+	address := net.JoinHostPort(ip, strconv.Itoa(probe.Port))
+
+	switch probe.Protocol {
+	case "tcp":
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		if err != nil {
+			return result
+		}
+		conn.Close()
+		result.Reachable = true
+
+	case "udp":
+		conn, err := net.DialTimeout("udp", address, timeout)
+		if err != nil {
+			result.Err = fmt.Errorf("failed to open UDP socket: %w", err)
+			return result
+		}
+		defer conn.Close()
+
+		// A reply means open, ICMP port unreachable means closed, and
+		// silence is the normal case for most UDP services
+		_ = conn.SetDeadline(time.Now().Add(timeout))
+		if _, err := conn.Write([]byte{0}); err != nil {
+			result.Err = fmt.Errorf("failed to send UDP probe: %w", err)
+			return result
+		}
+		buf := make([]byte, 512)
+		_, err = conn.Read(buf)
+		switch {
+		case err == nil:
+			result.Reachable = true
+		case errors.Is(err, syscall.ECONNREFUSED):
+			result.Reachable = false
+		default:
+			result.Inconclusive = true
+		}
+
+	default:
+		result.Err = fmt.Errorf("unsupported protocol %q", probe.Protocol)
+	}
+
+	return result
+}
@@ -0,0 +1,64 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ErrNoPTR is returned by LookupReverseDNS when the address has no PTR record,
+// as opposed to a lookup that failed
+var ErrNoPTR = errors.New("no PTR record")
+
+// ReverseDNSResult holds the PTR record of an address and whether it forward-confirms
+type ReverseDNSResult struct {
+	Hostname         string
+	ForwardConfirmed bool // the hostname resolves back to the original address (FCrDNS)
+}
+
+// LookupReverseDNS resolves the PTR record of ip and checks that the returned
+// hostname resolves back to the same address. An empty resolver uses the
+// system configuration. A missing record is reported as ErrNoPTR.
+func LookupReverseDNS(ip, resolver string) (*ReverseDNSResult, error) {
+	target := net.ParseIP(ip)
+	if target == nil {
+		return nil, fmt.Errorf("invalid IP address: %s", ip)
+	}
+
+	r := newResolver(resolver)
+	ctx, cancel := context.WithTimeout(context.Background(), dnsTimeout)
+	defer cancel()
+
+	names, err := r.LookupAddr(ctx, ip)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return nil, fmt.Errorf("%w for %s", ErrNoPTR, ip)
+		}
+		return nil, fmt.Errorf("failed to resolve PTR record: %w", err)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w for %s", ErrNoPTR, ip)
+	}
+
+	result := &ReverseDNSResult{Hostname: strings.TrimSuffix(names[0], ".")}
+
+	// Forward-confirm: any PTR name resolving back to the address counts
+	for _, name := range names {
+		addrs, err := r.LookupIPAddr(ctx, name)
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if addr.IP.Equal(target) {
+				result.Hostname = strings.TrimSuffix(name, ".")
+				result.ForwardConfirmed = true
+				return result, nil
+			}
+		}
+	}
+
+	return result, nil
+}
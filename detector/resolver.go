@@ -0,0 +1,30 @@
+package detector
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// dnsTimeout bounds every DNS lookup made by the detector
+const dnsTimeout = 5 * time.Second
+
+// newResolver returns a resolver that queries the given DNS server
+// ("host" or "host:port"), or the system resolver if addr is empty
+func newResolver(addr string) *net.Resolver {
+	if addr == "" {
+		return net.DefaultResolver
+	}
+
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "53")
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			d := net.Dialer{Timeout: dnsTimeout}
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
@@ -0,0 +1,100 @@
+package detector
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLSOptions customises the TLS connection to a detection service
+type TLSOptions struct {
+	CAFile     string   // PEM bundle of additional trusted CAs
+	CertFile   string   // client certificate for mutual TLS
+	KeyFile    string   // client key for mutual TLS
+	MinVersion string   // "1.0" to "1.3"
+	Pins       []string // base64 SHA-256 hashes of an acceptable SPKI, optionally prefixed "sha256/"
+	ServerName string   // SNI and verification name override
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// BuildTLSConfig creates a tls.Config from the given options
+func BuildTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: opts.ServerName,
+	}
+
+	if opts.MinVersion != "" {
+		version, ok := tlsVersions[strings.TrimPrefix(opts.MinVersion, "TLS")]
+		if !ok {
+			return nil, fmt.Errorf("unsupported TLS version %q", opts.MinVersion)
+		}
+		cfg.MinVersion = version
+	}
+
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", opts.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(opts.Pins) > 0 {
+		pins := make(map[string]bool, len(opts.Pins))
+		for _, pin := range opts.Pins {
+			pins[strings.TrimPrefix(strings.TrimSpace(pin), "sha256/")] = true
+		}
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyPins(cs, pins)
+		}
+	}
+
+	return cfg, nil
+}
+
+// ErrPinMismatch is returned when a service's certificate chain does not
+// contain a pinned public key
+var ErrPinMismatch = errors.New("certificate public key does not match any configured pin")
+
+// verifyPins accepts the connection if any certificate in a verified chain
+// has a pinned SPKI hash. Only chains built by the normal certificate
+// verification count; certificates the server merely sent are ignored, as
+// anyone can attach a copy of the pinned certificate.
+func verifyPins(cs tls.ConnectionState, pins map[string]bool) error {
+	for _, chain := range cs.VerifiedChains {
+		for _, cert := range chain {
+			sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+			if pins[base64.StdEncoding.EncodeToString(sum[:])] {
+				return nil
+			}
+		}
+	}
+	return ErrPinMismatch
+}
@@ -0,0 +1,160 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Event types a hook can subscribe to
+const (
+	EventChange   = "change"
+	EventFailure  = "failure"
+	EventRecovery = "recovery"
+)
+
+// maxOutput bounds the captured output of a hook
+const maxOutput = 4096
+
+// Hook is a local command run when an event occurs
+type Hook struct {
+	Name    string
+	Command string   // executed with /bin/sh -c
+	Events  []string // defaults to EventChange
+	Timeout time.Duration
+}
+
+// Event describes what triggered the hooks; it is passed to each command as
+// IPD_* environment variables and as JSON on stdin
+type Event struct {
+	Type      string `json:"event"`
+	Family    string `json:"family,omitempty"` // "ipv4" or "ipv6"
+	OldIP     string `json:"old_ip,omitempty"`
+	NewIP     string `json:"new_ip,omitempty"`
+	Hostname  string `json:"hostname"`
+	Service   string `json:"service,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Timestamp string `json:"timestamp"`
+}
+
+// Result holds the outcome of one hook run
+type Result struct {
+	Hook     string
+	Event    Event
+	ExitCode int // -1 if the command could not be run or was killed
+	Output   string
+	Duration time.Duration
+	Err      error
+}
+
+// subscribed reports whether the hook runs for the given event type
+func (h Hook) subscribed(eventType string) bool {
+	if len(h.Events) == 0 {
+		return eventType == EventChange
+	}
+	for _, e := range h.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Run executes every subscribed hook for each event, with at most
+// concurrency commands running at once. Results are ordered by event, then hook.
+func Run(hooks []Hook, events []Event, concurrency int) []Result {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	type job struct {
+		hook  Hook
+		event Event
+	}
+	var jobs []job
+	for _, event := range events {
+		for _, hook := range hooks {
+			if hook.subscribed(event.Type) {
+				jobs = append(jobs, job{hook, event})
+			}
+		}
+	}
+
+	results := make([]Result, len(jobs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, j := range jobs {
+		wg.Add(1)
+		go func(i int, j job) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = runHook(j.hook, j.event)
+		}(i, j)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// runHook executes a single hook command
+func runHook(hook Hook, event Event) Result {
+	result := Result{Hook: hook.Name, Event: event, ExitCode: -1}
+
+	input, err := json.Marshal(event)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to serialize event: %w", err)
+		return result
+	}
+
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := ShellCommand(ctx, hook.Command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(),
+		"IPD_EVENT="+event.Type,
+		"IPD_FAMILY="+event.Family,
+		"IPD_OLD_IP="+event.OldIP,
+		"IPD_NEW_IP="+event.NewIP,
+		"IPD_HOSTNAME="+event.Hostname,
+		"IPD_SERVICE="+event.Service,
+		"IPD_ERROR="+event.Error,
+	)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	start := time.Now()
+	err = cmd.Run()
+	result.Duration = time.Since(start)
+
+	result.Output = output.String()
+	if len(result.Output) > maxOutput {
+		result.Output = result.Output[:maxOutput]
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		result.Err = fmt.Errorf("timed out after %s", timeout)
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		result.Err = fmt.Errorf("exited with code %d", result.ExitCode)
+	case err != nil:
+		result.Err = fmt.Errorf("failed to run: %w", err)
+	default:
+		result.ExitCode = 0
+	}
+	return result
+}
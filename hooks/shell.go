@@ -0,0 +1,20 @@
+package hooks
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// waitDelay bounds how long a finished or killed command's output is read,
+// so background children holding the pipes open cannot block the caller
+const waitDelay = time.Second
+
+// ShellCommand returns a /bin/sh command for command that, when ctx expires,
+// kills the shell together with every process it started
+func ShellCommand(ctx context.Context, command string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	setProcessGroup(cmd)
+	cmd.WaitDelay = waitDelay
+	return cmd
+}
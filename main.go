@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
@@ -13,6 +14,7 @@ import (
 
 	"ip_detector/config"
 	"ip_detector/detector"
+	"ip_detector/hooks"
 	"ip_detector/notifier"
 )
 
@@ -23,6 +25,8 @@ func main() {
 	reconfigure := flag.Bool("reconfigure", false, "Reconfigure the application")
 	daemon := flag.Bool("daemon", false, "Run in daemon mode (check IP periodically)")
 	interval := flag.Int("interval", 300, "Check interval in seconds for daemon mode (default: 300)")
+	stats := flag.Bool("stats", false, "Show time spent on each known IP/link from history")
+	encrypt := flag.Bool("encrypt", false, "Encrypt a secret read from stdin for use in notifier options")
 	flag.Parse()
 
 	// Get hostname for notifications
@@ -38,6 +42,10 @@ func main() {
 			cfg, err := config.Load()
 			if err == nil {
 				service = cfg.SelectedService
+				if err := configureServices(cfg); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
 			}
 		}
 
@@ -53,8 +61,10 @@ func main() {
 		}
 
 		// Detect IPv6
-		ipv6, v6Service, _ := detector.DetectIPv6WithFallback(service)
-		if ipv6 == "" {
+		ipv6, v6Service, err := detector.DetectIPv6WithFallback(service)
+		if err != nil {
+			fmt.Printf("IPv6: Not detected (%v)\n", err)
+		} else if ipv6 == "" {
 			fmt.Println("IPv6: Not available")
 		} else {
 			fmt.Printf("IPv6: %s (via %s)\n", ipv6, v6Service)
@@ -62,6 +72,33 @@ func main() {
 		return
 	}
 
+	// Handle secret encryption
+	if *encrypt {
+		if err := encryptSecret(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encrypt secret: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle stats mode
+	if *stats {
+		if err := showLabelStats(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to show stats: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Without a configuration, notification URLs from the environment are
+	// enough to run, e.g. in a container
+	if !config.Exists() && !*reconfigure && len(config.EnvNotifyURLs()) > 0 {
+		if err := createDefaultConfig(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create configuration: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Check if first run or reconfigure requested
 	if !config.Exists() || *reconfigure {
 		if err := runSetupWizard(); err != nil {
@@ -104,6 +141,17 @@ func main() {
 	}
 }
 
+// createDefaultConfig saves a configuration with default settings, for runs
+// that are configured entirely through IPD_NOTIFY_URLS
+func createDefaultConfig() error {
+	cfg := &config.Config{SelectedService: "ipify"}
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Created a default configuration; notifications are sent to the URLs in %s\n", config.NotifyURLsEnv)
+	return nil
+}
+
 func runSetupWizard() error {
 	reader := bufio.NewReader(os.Stdin)
 
@@ -131,6 +179,19 @@ func runSetupWizard() error {
 	fmt.Println("─────────────────────────────────────────")
 	fmt.Println()
 
+	// Get Telegram Bot API URL
+	fmt.Printf("Telegram Bot API URL (press Enter for %s): ", notifier.DefaultTelegramAPIURL)
+	apiURL, _ := reader.ReadString('\n')
+	apiURL = strings.TrimSpace(apiURL)
+	if apiURL == notifier.DefaultTelegramAPIURL {
+		apiURL = ""
+	}
+	if apiURL != "" {
+		if err := notifier.ValidateTelegramAPIURL(apiURL); err != nil {
+			return err
+		}
+	}
+
 	// Get Telegram Bot Token
 	fmt.Print("Enter your Telegram Bot Token: ")
 	botToken, _ := reader.ReadString('\n')
@@ -139,19 +200,39 @@ func runSetupWizard() error {
 		return fmt.Errorf("bot token cannot be empty")
 	}
 
-	// Get Telegram Chat ID
-	fmt.Print("Enter your Telegram Chat ID: ")
+	// Validate the token
+	bot := notifier.NewTelegramNotifier(botToken, "")
+	if apiURL != "" {
+		bot.APIURL = apiURL
+	}
+	me, err := bot.GetMe()
+	if err != nil {
+		return fmt.Errorf("invalid bot token: %w", err)
+	}
+	fmt.Printf("✅ Bot token is valid: @%s (%s)\n", me.Username, me.FirstName)
+
+	// Get Telegram Chat ID, or discover it from a message to the bot
+	fmt.Print("Enter your Telegram Chat ID (press Enter to detect it automatically): ")
 	chatID, _ := reader.ReadString('\n')
 	chatID = strings.TrimSpace(chatID)
 	if chatID == "" {
-		return fmt.Errorf("chat ID cannot be empty")
+		chatID, err = selectChatID(reader, bot, me.Username)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Create and save configuration
-	_, err = config.CreateNew(selectedService, botToken, chatID)
+	newCfg, err := config.CreateNew(selectedService, botToken, chatID)
 	if err != nil {
 		return fmt.Errorf("failed to create configuration: %w", err)
 	}
+	if apiURL != "" {
+		newCfg.TelegramAPIURL = apiURL
+		if err := newCfg.Save(); err != nil {
+			return fmt.Errorf("failed to create configuration: %w", err)
+		}
+	}
 
 	// Test the configuration
 	fmt.Println("\n🔄 Testing Telegram connection...")
@@ -172,23 +253,18 @@ func runSetupWizard() error {
 }
 
 func sendTestNotification(cfg *config.Config, hostname string) error {
-	botToken, err := cfg.GetBotToken()
-	if err != nil {
-		return fmt.Errorf("failed to decrypt bot token: %w", err)
-	}
-
-	chatID, err := cfg.GetChatID()
-	if err != nil {
-		return fmt.Errorf("failed to decrypt chat ID: %w", err)
-	}
-
-	tn := notifier.NewTelegramNotifier(botToken, chatID)
-	return tn.SendTestNotification(hostname)
+	return notifyAll(cfg, func(n notifier.Notifier) error {
+		return n.SendTest(hostname)
+	})
 }
 
 func checkAndNotify(cfg *config.Config, hostname string) error {
 	now := time.Now()
 
+	if err := configureServices(cfg); err != nil {
+		return err
+	}
+
 	// Detect IPv4
 	ipv4, v4Service, err := detector.DetectIPv4WithFallback(cfg.SelectedService)
 	if err != nil {
@@ -199,28 +275,48 @@ func checkAndNotify(cfg *config.Config, hostname string) error {
 	}
 
 	// Detect IPv6
-	ipv6, v6Service, _ := detector.DetectIPv6WithFallback(cfg.SelectedService)
-	if ipv6 != "" {
+	ipv6, v6Service, v6Err := detector.DetectIPv6WithFallback(cfg.SelectedService)
+	if v6Err != nil {
+		fmt.Printf("⚠️  IPv6 detection failed: %v\n", v6Err)
+	} else if ipv6 != "" {
 		fmt.Printf("IPv6: %s (via %s)\n", ipv6, v6Service)
 	} else {
 		fmt.Println("IPv6: Not available")
 	}
 
-	// Check for changes
-	ipv4Status := notifier.IPStatus{
-		Current:  ipv4,
-		Previous: cfg.LastKnownIPv4,
-		Changed:  ipv4 != "" && ipv4 != cfg.LastKnownIPv4,
+	// Report detection failures and recoveries. A failed pin check is a
+	// failure even if the other family was detected.
+	var detectErr error
+	if errors.Is(err, detector.ErrPinMismatch) || errors.Is(v6Err, detector.ErrPinMismatch) {
+		detectErr = fmt.Errorf("certificate pin check failed: %w", errors.Join(err, v6Err))
+	} else if ipv4 == "" && ipv6 == "" {
+		detectErr = fmt.Errorf("no IPv4 or IPv6 address could be detected: %v", err)
+	}
+	if err := reportDetectionHealth(cfg, hostname, detectErr, now); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
 	}
 
-	ipv6Status := notifier.IPStatus{
-		Current:  ipv6,
-		Previous: cfg.LastKnownIPv6,
-		Changed:  ipv6 != "" && ipv6 != cfg.LastKnownIPv6,
+	// Sync templates, publish the outcome, refresh status messages and send a
+	// due chart once this check is complete
+	defer func() {
+		renderTemplates(cfg, hostname)
+		publishState(cfg, hostname, ipv4, ipv6, now)
+		updateStatusMessages(cfg, hostname, ipv4, ipv6, now)
+		sendScheduledChart(cfg, hostname, now)
+	}()
+	if detectErr != nil {
+		return detectErr
 	}
 
+	// Check for changes
+	ipv4Status := buildIPStatus(cfg, ipv4, cfg.LastKnownIPv4, v4Service)
+	ipv6Status := buildIPStatus(cfg, ipv6, cfg.LastKnownIPv6, v6Service)
+
 	// If anything changed, send notification
 	if ipv4Status.Changed || ipv6Status.Changed {
+		runChangeChecks(cfg, "ipv4", &ipv4Status)
+		runChangeChecks(cfg, "ipv6", &ipv6Status)
+
 		// Update config
 		if ipv4 != "" {
 			cfg.LastKnownIPv4 = ipv4
@@ -229,44 +325,181 @@ func checkAndNotify(cfg *config.Config, hostname string) error {
 			cfg.LastKnownIPv6 = ipv6
 		}
 		cfg.LastChecked = now.Format(time.RFC3339)
+		cfg.LastChanged = now.Format(time.RFC3339)
+		if len(ipv4Status.Ports) > 0 || len(ipv6Status.Ports) > 0 {
+			cfg.LastPortCheck = now.Format(time.RFC3339)
+		}
 
 		if err := cfg.Save(); err != nil {
 			return fmt.Errorf("failed to save configuration: %w", err)
 		}
 
+		// Run hooks for each changed family
+		var hookEvents []hooks.Event
+		if ipv4Status.Changed {
+			hookEvents = append(hookEvents, changeHookEvent(hostname, "ipv4", ipv4Status, now))
+		}
+		if ipv6Status.Changed {
+			hookEvents = append(hookEvents, changeHookEvent(hostname, "ipv6", ipv6Status, now))
+		}
+		hookRecords := runHooks(cfg, hookEvents)
+
 		// Add history entries
 		if ipv4Status.Changed {
-			if err := config.AddHistoryEntry("ipv4", ipv4Status.Previous, ipv4); err != nil {
+			entry := historyEntry("ipv4", ipv4Status)
+			entry.Hooks = hookRecords["ipv4"]
+			if err := config.AddHistoryRecord(entry); err != nil {
 				fmt.Printf("⚠️  Warning: Failed to save IPv4 history: %v\n", err)
 			}
 		}
 		if ipv6Status.Changed {
-			if err := config.AddHistoryEntry("ipv6", ipv6Status.Previous, ipv6); err != nil {
+			entry := historyEntry("ipv6", ipv6Status)
+			entry.Hooks = hookRecords["ipv6"]
+			if err := config.AddHistoryRecord(entry); err != nil {
 				fmt.Printf("⚠️  Warning: Failed to save IPv6 history: %v\n", err)
 			}
 		}
 
 		// Send combined notification
-		botToken, err := cfg.GetBotToken()
-		if err != nil {
-			return fmt.Errorf("failed to decrypt bot token: %w", err)
+		event := notifier.ChangeEvent{
+			Hostname:  hostname,
+			IPv4:      ipv4Status,
+			IPv6:      ipv6Status,
+			Timestamp: now,
 		}
-
-		chatID, err := cfg.GetChatID()
-		if err != nil {
-			return fmt.Errorf("failed to decrypt chat ID: %w", err)
+		if notificationsMuted(cfg, now) {
+			return nil
 		}
-
-		tn := notifier.NewTelegramNotifier(botToken, chatID)
-		if err := tn.SendCombinedIPNotification(hostname, ipv4Status, ipv6Status, now); err != nil {
-			return fmt.Errorf("failed to send notification: %w", err)
+		if err := notifyAll(cfg, func(n notifier.Notifier) error {
+			return n.SendChange(event)
+		}); err != nil {
+			return err
 		}
 
 		fmt.Println("✅ Notification sent.")
 	} else {
 		fmt.Println("No IP changes detected.")
+		if portCheckDue(cfg, now) {
+			return runPeriodicPortCheck(cfg, hostname, ipv4Status, ipv6Status, now)
+		}
+	}
+
+	return nil
+}
+
+// buildIPStatus compares a detected address with the last known one and
+// resolves catalogue labels for both
+func buildIPStatus(cfg *config.Config, current, previous, service string) notifier.IPStatus {
+	status := notifier.IPStatus{
+		Current:  current,
+		Previous: previous,
+		Changed:  current != "" && current != previous,
+		Service:  service,
+	}
+	if current != "" {
+		status.Label = cfg.LabelFor(current)
+	}
+	if previous != "" {
+		status.PreviousLabel = cfg.LabelFor(previous)
+	}
+	if status.Changed {
+		status.Transition = cfg.ClassifyTransition(previous, current)
+	}
+	return status
+}
+
+// historyEntry converts a changed IP status into a history record
+func historyEntry(ipType string, status notifier.IPStatus) config.IPHistoryEntry {
+	entry := config.IPHistoryEntry{
+		Type:     ipType,
+		OldIP:    status.Previous,
+		NewIP:    status.Current,
+		OldLabel: status.PreviousLabel,
+		NewLabel: status.Label,
+		Event:    status.Transition,
+		PTR:      status.PTR,
+		FCrDNS:   status.FCrDNS,
+	}
+	for _, port := range status.Ports {
+		if port.Inconclusive {
+			continue
+		}
+		if port.Reachable {
+			entry.PortsReachable = append(entry.PortsReachable, port.Service)
+		} else {
+			entry.PortsUnreachable = append(entry.PortsUnreachable, port.Service)
+		}
+	}
+	if status.DNSBLChecked {
+		entry.DNSBLListed = status.DNSBLListed
+		entry.DNSBLErrors = status.DNSBLErrors
+		switch {
+		case len(status.DNSBLListed) > 0:
+			entry.DNSBLStatus = "listed"
+		case len(status.DNSBLErrors) >= status.DNSBLLists:
+			entry.DNSBLStatus = "unavailable"
+		case len(status.DNSBLErrors) > 0:
+			entry.DNSBLStatus = "incomplete"
+		default:
+			entry.DNSBLStatus = "clean"
+		}
+	}
+	return entry
+}
+
+// encryptSecret reads a secret from stdin and prints its encrypted form
+func encryptSecret() error {
+	fmt.Fprint(os.Stderr, "Enter secret: ")
+	secret, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && secret == "" {
+		return fmt.Errorf("failed to read secret: %w", err)
 	}
 
+	encrypted, err := config.EncryptSecret(strings.TrimRight(secret, "\r\n"))
+	if err != nil {
+		return err
+	}
+	fmt.Println(encrypted)
+	return nil
+}
+
+// showLabelStats prints the time spent on each known link per address family
+func showLabelStats() error {
+	cfg := &config.Config{}
+	if config.Exists() {
+		loaded, err := config.Load()
+		if err != nil {
+			return err
+		}
+		cfg = loaded
+	}
+
+	history, err := config.LoadHistory()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, family := range []struct{ key, name string }{{"ipv4", "IPv4"}, {"ipv6", "IPv6"}} {
+		fmt.Printf("%s:\n", family.name)
+		stats := cfg.LabelStats(history, family.key, now)
+		if len(stats) == 0 {
+			fmt.Println("  No history recorded.")
+			continue
+		}
+
+		var total time.Duration
+		for _, s := range stats {
+			total += s.Duration
+		}
+		for _, s := range stats {
+			share := 0.0
+			if total > 0 {
+				share = float64(s.Duration) / float64(total) * 100
+			}
+			fmt.Printf("  %-30s %12s  %5.1f%%\n", s.Label, s.Duration.Round(time.Minute), share)
+		}
+	}
 	return nil
 }
 
@@ -279,10 +512,22 @@ func runDaemon(cfg *config.Config, hostname string, intervalSeconds int) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	ticker := time.NewTicker(time.Duration(intervalSeconds) * time.Second)
+	interval := time.Duration(intervalSeconds) * time.Second
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	// Answer bot commands; they are handled in this loop so that they never
+	// race with a scheduled check over the configuration
+	bot := telegramBot(cfg)
+	var commands chan botCommand
+	if bot != nil {
+		commands = make(chan botCommand)
+		go pollBotCommands(bot, commands)
+		fmt.Println("Telegram bot commands enabled.")
+	}
+
 	// Run immediately on start
+	schedule := &checkSchedule{Last: time.Now(), Next: time.Now().Add(interval)}
 	if err := checkAndNotify(cfg, hostname); err != nil {
 		fmt.Printf("Error: %v\n", err)
 	}
@@ -293,15 +538,31 @@ func runDaemon(cfg *config.Config, hostname string, intervalSeconds int) {
 			fmt.Println("\nReceived shutdown signal. Exiting gracefully...")
 			return
 		case <-ticker.C:
+			schedule.Last = time.Now()
+			schedule.Next = schedule.Last.Add(interval)
+
 			// Reload config in case it was modified
-			cfg, err := config.Load()
+			loaded, err := config.Load()
 			if err != nil {
 				fmt.Printf("Error loading config: %v\n", err)
 				continue
 			}
+			cfg = loaded
 			if err := checkAndNotify(cfg, hostname); err != nil {
 				fmt.Printf("Error: %v\n", err)
 			}
+		case cmd := <-commands:
+			if loaded, err := config.Load(); err == nil {
+				cfg = loaded
+			}
+			fmt.Printf("Telegram bot: /%s\n", cmd.Name)
+			reply := handleBotCommand(cfg, hostname, bot, cmd, schedule)
+			if reply == "" {
+				continue
+			}
+			if err := bot.Reply(cmd.Message, reply); err != nil {
+				fmt.Printf("⚠️  Telegram bot reply failed: %v\n", err)
+			}
 		}
 	}
 }
@@ -0,0 +1,310 @@
+package mqtt
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+)
+
+// Protocol levels
+const (
+	Version311 = 4
+	Version5   = 5
+)
+
+// Control packet types
+const (
+	packetConnect    = 1
+	packetConnAck    = 2
+	packetPublish    = 3
+	packetPubAck     = 4
+	packetDisconnect = 14
+)
+
+// Options configure a connection to a broker
+type Options struct {
+	Broker   string // "tcp://host:1883", "ssl://host:8883", "mqtts://..." or "tls://..."
+	ClientID string
+	Username string
+	Password string
+	Version  byte        // Version311 (default) or Version5
+	TLS      *tls.Config // used for ssl/mqtts/tls brokers; nil uses the defaults
+	Timeout  time.Duration
+}
+
+// Client is a minimal publish-only MQTT client
+type Client struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	version byte
+	timeout time.Duration
+	nextID  uint16
+}
+
+// Connect dials the broker and performs the MQTT handshake
+func Connect(opts Options) (*Client, error) {
+	u, err := url.Parse(opts.Broker)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid broker URL %q", opts.Broker)
+	}
+
+	version := opts.Version
+	if version == 0 {
+		version = Version311
+	}
+	if version != Version311 && version != Version5 {
+		return nil, fmt.Errorf("unsupported MQTT protocol version %d", version)
+	}
+	// MQTT 3.1.1 only allows a password together with a user name
+	if version == Version311 && opts.Password != "" && opts.Username == "" {
+		return nil, fmt.Errorf("MQTT 3.1.1 does not allow a password without a username")
+	}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		address := u.Host
+		if u.Port() == "" {
+			address = net.JoinHostPort(u.Hostname(), "1883")
+		}
+		conn, err = dialer.Dial("tcp", address)
+	case "ssl", "tls", "mqtts":
+		address := u.Host
+		if u.Port() == "" {
+			address = net.JoinHostPort(u.Hostname(), "8883")
+		}
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if opts.TLS != nil {
+			tlsConfig = opts.TLS.Clone()
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = u.Hostname()
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	default:
+		return nil, fmt.Errorf("unsupported broker scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to broker: %w", err)
+	}
+
+	c := &Client{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		version: version,
+		timeout: timeout,
+	}
+	if err := c.handshake(opts); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// handshake sends CONNECT and waits for a successful CONNACK
+func (c *Client) handshake(opts Options) error {
+	var body []byte
+	body = appendString(body, "MQTT")
+	body = append(body, c.version)
+
+	flags := byte(0x02) // clean session / clean start
+	if opts.Username != "" {
+		flags |= 0x80
+	}
+	if opts.Password != "" {
+		flags |= 0x40
+	}
+	body = append(body, flags)
+	body = binary.BigEndian.AppendUint16(body, 60) // keep alive, seconds
+	if c.version == Version5 {
+		body = append(body, 0) // no properties
+	}
+
+	body = appendString(body, opts.ClientID)
+	if opts.Username != "" {
+		body = appendString(body, opts.Username)
+	}
+	if opts.Password != "" {
+		body = appendString(body, opts.Password)
+	}
+
+	if err := c.writePacket(packetConnect<<4, body); err != nil {
+		return fmt.Errorf("failed to send CONNECT: %w", err)
+	}
+
+	packetType, payload, err := c.readPacket()
+	if err != nil {
+		return fmt.Errorf("failed to read CONNACK: %w", err)
+	}
+	if packetType != packetConnAck || len(payload) < 2 {
+		return fmt.Errorf("unexpected response to CONNECT (packet type %d)", packetType)
+	}
+	if code := payload[1]; code != 0 {
+		return fmt.Errorf("broker refused connection (%s)", connackReason(c.version, code))
+	}
+	return nil
+}
+
+// Publish sends a message with QoS 0 or 1 and, for QoS 1, waits for the broker's PUBACK
+func (c *Client) Publish(topic string, payload []byte, qos byte, retain bool) error {
+	if qos > 1 {
+		return fmt.Errorf("unsupported QoS %d", qos)
+	}
+
+	header := byte(packetPublish<<4) | qos<<1
+	if retain {
+		header |= 0x01
+	}
+
+	var body []byte
+	body = appendString(body, topic)
+	var id uint16
+	if qos > 0 {
+		c.nextID++
+		if c.nextID == 0 {
+			c.nextID = 1
+		}
+		id = c.nextID
+		body = binary.BigEndian.AppendUint16(body, id)
+	}
+	if c.version == Version5 {
+		body = append(body, 0) // no properties
+	}
+	body = append(body, payload...)
+
+	if err := c.writePacket(header, body); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", topic, err)
+	}
+	if qos == 0 {
+		return nil
+	}
+
+	packetType, ack, err := c.readPacket()
+	if err != nil {
+		return fmt.Errorf("failed to read PUBACK: %w", err)
+	}
+	if packetType != packetPubAck || len(ack) < 2 || binary.BigEndian.Uint16(ack) != id {
+		return fmt.Errorf("unexpected response to PUBLISH (packet type %d)", packetType)
+	}
+	// MQTT 5 brokers may append a reason code; 0x80 and above are failures
+	if c.version == Version5 && len(ack) > 2 && ack[2] >= 0x80 {
+		return fmt.Errorf("broker rejected publish to %s (reason code 0x%02x)", topic, ack[2])
+	}
+	return nil
+}
+
+// Disconnect sends DISCONNECT and closes the connection
+func (c *Client) Disconnect() error {
+	err := c.writePacket(packetDisconnect<<4, nil)
+	if closeErr := c.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writePacket writes a control packet with the given fixed header byte
+func (c *Client) writePacket(header byte, body []byte) error {
+	packet := append([]byte{header}, encodeLength(len(body))...)
+	packet = append(packet, body...)
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	_, err := c.conn.Write(packet)
+	return err
+}
+
+// readPacket reads a control packet and returns its type and variable part
+func (c *Client) readPacket() (byte, []byte, error) {
+	_ = c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+
+	header, err := c.reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, fmt.Errorf("malformed remaining length")
+		}
+		b, err := c.reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(b&0x7f) * multiplier
+		if b&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, body); err != nil {
+		return 0, nil, err
+	}
+	return header >> 4, body, nil
+}
+
+// encodeLength encodes the remaining length as an MQTT variable byte integer
+func encodeLength(n int) []byte {
+	var out []byte
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+		if n == 0 {
+			return out
+		}
+	}
+}
+
+// appendString appends a length-prefixed UTF-8 string
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// connackReason describes a CONNACK return/reason code
+func connackReason(version, code byte) string {
+	if version == Version311 {
+		switch code {
+		case 1:
+			return "unacceptable protocol version"
+		case 2:
+			return "identifier rejected"
+		case 3:
+			return "server unavailable"
+		case 4:
+			return "bad user name or password"
+		case 5:
+			return "not authorized"
+		}
+	} else {
+		switch code {
+		case 0x84:
+			return "unsupported protocol version"
+		case 0x85:
+			return "client identifier not valid"
+		case 0x86:
+			return "bad user name or password"
+		case 0x87:
+			return "not authorized"
+		case 0x88:
+			return "server unavailable"
+		}
+	}
+	return fmt.Sprintf("code 0x%02x", code)
+}
@@ -0,0 +1,163 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Unavailable is published for an address family that was not detected;
+// an empty retained payload would delete the retained message instead
+const Unavailable = "unavailable"
+
+// State is the detector status published on every check
+type State struct {
+	Hostname   string
+	IPv4       string
+	IPv6       string
+	LastChange time.Time // zero if no change was recorded yet
+	LastCheck  time.Time
+	Healthy    bool // IP detection is working
+}
+
+// PublishOptions control the topics a State is published to
+type PublishOptions struct {
+	TopicPrefix     string // default "ip_detector/<hostname>"
+	Discovery       bool   // publish Home Assistant MQTT discovery config
+	DiscoveryPrefix string // default "homeassistant"
+	QoS             byte
+}
+
+// sensor describes one published value and its Home Assistant entity
+type sensor struct {
+	key         string // topic suffix and object ID
+	name        string
+	component   string // "sensor" or "binary_sensor"
+	icon        string
+	deviceClass string
+	value       string
+}
+
+// discoveryConfig is the Home Assistant MQTT discovery payload
+type discoveryConfig struct {
+	Name        string          `json:"name"`
+	UniqueID    string          `json:"unique_id"`
+	ObjectID    string          `json:"object_id"`
+	StateTopic  string          `json:"state_topic"`
+	Icon        string          `json:"icon,omitempty"`
+	DeviceClass string          `json:"device_class,omitempty"`
+	PayloadOn   string          `json:"payload_on,omitempty"`
+	PayloadOff  string          `json:"payload_off,omitempty"`
+	Device      discoveryDevice `json:"device"`
+}
+
+// discoveryDevice groups all sensors of one host in Home Assistant
+type discoveryDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model"`
+}
+
+// invalidIDChars matches characters not allowed in discovery node/object IDs
+var invalidIDChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// PublishState connects to the broker and publishes the state as retained
+// topics, plus Home Assistant discovery config if enabled
+func PublishState(opts Options, pub PublishOptions, state State) error {
+	nodeID := invalidIDChars.ReplaceAllString(state.Hostname, "_")
+	prefix := strings.TrimRight(pub.TopicPrefix, "/")
+	if prefix == "" {
+		prefix = "ip_detector/" + nodeID
+	}
+	discoveryPrefix := strings.TrimRight(pub.DiscoveryPrefix, "/")
+	if discoveryPrefix == "" {
+		discoveryPrefix = "homeassistant"
+	}
+
+	var lastChange string
+	if !state.LastChange.IsZero() {
+		lastChange = state.LastChange.Format(time.RFC3339)
+	}
+	health := "ok"
+	if !state.Healthy {
+		health = "failing"
+	}
+
+	sensors := []sensor{
+		{key: "last_check", name: "Last check", component: "sensor", icon: "mdi:clock-check-outline",
+			deviceClass: "timestamp", value: state.LastCheck.Format(time.RFC3339)},
+		{key: "health", name: "Detection problem", component: "binary_sensor", deviceClass: "problem", value: health},
+	}
+	// Keep the last retained addresses while detection is failing
+	if state.Healthy {
+		sensors = append(sensors,
+			sensor{key: "ipv4", name: "Public IPv4", component: "sensor", icon: "mdi:ip-network", value: addressValue(state.IPv4)},
+			sensor{key: "ipv6", name: "Public IPv6", component: "sensor", icon: "mdi:ip-network-outline", value: addressValue(state.IPv6)},
+		)
+	}
+	if lastChange != "" {
+		sensors = append(sensors, sensor{key: "last_change", name: "Last IP change", component: "sensor",
+			icon: "mdi:swap-horizontal", deviceClass: "timestamp", value: lastChange})
+	}
+
+	if opts.ClientID == "" {
+		opts.ClientID = "ip_detector-" + nodeID
+	}
+	client, err := Connect(opts)
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect()
+
+	for _, s := range sensors {
+		if pub.Discovery {
+			if err := publishDiscovery(client, pub.QoS, discoveryPrefix, nodeID, prefix, state.Hostname, s); err != nil {
+				return err
+			}
+		}
+		if err := client.Publish(prefix+"/"+s.key, []byte(s.value), pub.QoS, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addressValue returns the payload for an address, or Unavailable if empty
+func addressValue(ip string) string {
+	if ip == "" {
+		return Unavailable
+	}
+	return ip
+}
+
+// publishDiscovery publishes the Home Assistant discovery config of one sensor
+func publishDiscovery(client *Client, qos byte, discoveryPrefix, nodeID, prefix, hostname string, s sensor) error {
+	cfg := discoveryConfig{
+		Name:        s.name,
+		UniqueID:    fmt.Sprintf("ip_detector_%s_%s", nodeID, s.key),
+		ObjectID:    fmt.Sprintf("ip_detector_%s_%s", nodeID, s.key),
+		StateTopic:  prefix + "/" + s.key,
+		Icon:        s.icon,
+		DeviceClass: s.deviceClass,
+		Device: discoveryDevice{
+			Identifiers:  []string{"ip_detector_" + nodeID},
+			Name:         "IP Detector " + hostname,
+			Manufacturer: "ip_detector",
+			Model:        "Public IP monitor",
+		},
+	}
+	if s.component == "binary_sensor" {
+		cfg.PayloadOn = "failing"
+		cfg.PayloadOff = "ok"
+	}
+
+	payload, err := json.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to serialize discovery config: %w", err)
+	}
+	topic := fmt.Sprintf("%s/%s/%s/%s/config", discoveryPrefix, s.component, nodeID, s.key)
+	return client.Publish(topic, payload, qos, true)
+}
@@ -0,0 +1,199 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Embed colours per event kind
+const (
+	discordBlue   = 0x3498DB
+	discordOrange = 0xF39C12
+	discordRed    = 0xE74C3C
+	discordGreen  = 0x2ECC71
+	discordPurple = 0x9B59B6
+)
+
+// discordColors maps change event kinds to embed colours
+var discordColors = map[string]int{
+	KindInit:         discordBlue,
+	KindChange:       discordOrange,
+	KindFailover:     discordRed,
+	KindFailback:     discordGreen,
+	KindReachability: discordPurple,
+}
+
+// discordMaxRetries limits how often a rate-limited message is retried
+const discordMaxRetries = 3
+
+// DiscordOptions configure a "discord" notifier
+type DiscordOptions struct {
+	WebhookURL string `json:"webhook_url"`
+	Username   string `json:"username,omitempty"`   // override the webhook's display name
+	AvatarURL  string `json:"avatar_url,omitempty"` // override the webhook's avatar
+}
+
+// DiscordNotifier posts embeds to a Discord webhook
+type DiscordNotifier struct {
+	opts DiscordOptions
+}
+
+func init() {
+	Register("discord", func(options json.RawMessage) (Notifier, error) {
+		var opts DiscordOptions
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return NewDiscordNotifier(opts)
+	})
+	RegisterScheme("discord", "discord", parseDiscordURL)
+}
+
+// parseDiscordURL parses "discord://TOKEN@WEBHOOK_ID?username=...&avatar_url=..."
+func parseDiscordURL(u *url.URL) (interface{}, error) {
+	token, id := urlUsername(u), u.Host
+	if token == "" || id == "" {
+		return nil, fmt.Errorf("discord url needs a webhook token and ID: discord://TOKEN@WEBHOOK_ID")
+	}
+	q := u.Query()
+	return DiscordOptions{
+		WebhookURL: "https://discord.com/api/webhooks/" + url.PathEscape(id) + "/" + url.PathEscape(token),
+		Username:   q.Get("username"),
+		AvatarURL:  q.Get("avatar_url"),
+	}, nil
+}
+
+// NewDiscordNotifier creates a Discord notifier from its options
+func NewDiscordNotifier(opts DiscordOptions) (*DiscordNotifier, error) {
+	if opts.WebhookURL == "" {
+		return nil, fmt.Errorf("discord notifier needs a webhook_url")
+	}
+	return &DiscordNotifier{opts: opts}, nil
+}
+
+// discordField is a single name/value pair in an embed
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// discordEmbed is a Discord rich embed
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color"`
+	Fields      []discordField `json:"fields,omitempty"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+}
+
+// discordMessage is the webhook execute payload
+type discordMessage struct {
+	Username  string         `json:"username,omitempty"`
+	AvatarURL string         `json:"avatar_url,omitempty"`
+	Embeds    []discordEmbed `json:"embeds"`
+}
+
+// discordValue fits an embed field value within Discord's limits
+func discordValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+	if runes := []rune(s); len(runes) > 1024 {
+		s = string(runes[:1021]) + "..."
+	}
+	return s
+}
+
+// SendChange implements Notifier
+func (d *DiscordNotifier) SendChange(event ChangeEvent) error {
+	m := commonMarkMarkup{}
+	kind := event.Kind()
+	embed := discordEmbed{
+		Title:       kindEmoji[kind] + " " + event.Title(),
+		Description: strings.Join(formatTransitions(m, event), "\n"),
+		Color:       discordColors[kind],
+		Fields: []discordField{
+			{Name: "Host", Value: discordValue(m.code(event.Hostname))},
+			{Name: "IPv4", Value: discordValue(describeStatus(m, event.IPv4)), Inline: true},
+			{Name: "IPv6", Value: discordValue(describeStatus(m, event.IPv6)), Inline: true},
+		},
+		Timestamp: event.Timestamp.Format(time.RFC3339),
+	}
+	if lines := formatDetails(m, event); len(lines) > 0 {
+		embed.Fields = append(embed.Fields, discordField{Name: "Checks", Value: discordValue(strings.Join(lines, "\n"))})
+	}
+	embed.Fields = append(embed.Fields, discordField{Name: "Time", Value: event.Timestamp.Format(timeFormat)})
+
+	return d.send(embed)
+}
+
+// SendTest implements Notifier
+func (d *DiscordNotifier) SendTest(hostname string) error {
+	return d.send(discordEmbed{
+		Title:       "✅ IP Detector Test",
+		Description: "Discord notification is working correctly!",
+		Color:       discordGreen,
+		Fields:      []discordField{{Name: "Host", Value: discordValue(commonMarkMarkup{}.code(hostname))}},
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
+
+// SendFailure implements Notifier
+func (d *DiscordNotifier) SendFailure(event FailureEvent) error {
+	embed := discordEmbed{
+		Title:     "❌ " + event.Title(),
+		Color:     discordRed,
+		Fields:    []discordField{{Name: "Host", Value: discordValue(commonMarkMarkup{}.code(event.Hostname))}},
+		Timestamp: event.Timestamp.Format(time.RFC3339),
+	}
+	if event.Recovered {
+		embed.Title = "✅ " + event.Title()
+		embed.Color = discordGreen
+	} else {
+		embed.Fields = append(embed.Fields, discordField{Name: "Error", Value: discordValue(commonMarkMarkup{}.text(event.Error))})
+	}
+	embed.Fields = append(embed.Fields, discordField{Name: "Time", Value: event.Timestamp.Format(timeFormat)})
+
+	return d.send(embed)
+}
+
+// send posts an embed, waiting and retrying when Discord rate-limits the webhook
+func (d *DiscordNotifier) send(embed discordEmbed) error {
+	body, err := json.Marshal(discordMessage{
+		Username:  d.opts.Username,
+		AvatarURL: d.opts.AvatarURL,
+		Embeds:    []discordEmbed{embed},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to serialize discord message: %w", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		status, respBody, err := doRequest("POST", d.opts.WebhookURL, "application/json", body, nil)
+		if err != nil {
+			return fmt.Errorf("failed to send discord message: %w", err)
+		}
+		if status != http.StatusTooManyRequests || attempt >= discordMaxRetries {
+			return checkStatus("discord", status, respBody)
+		}
+
+		// Discord reports the wait in seconds (fractional) in the body
+		var rateLimit struct {
+			RetryAfter float64 `json:"retry_after"`
+		}
+		_ = json.Unmarshal(respBody, &rateLimit)
+		wait := time.Duration(rateLimit.RetryAfter * float64(time.Second))
+		if wait <= 0 {
+			wait = time.Second
+		}
+		if wait > time.Minute {
+			return fmt.Errorf("discord rate limit exceeded (retry after %s)", wait)
+		}
+		time.Sleep(wait)
+	}
+}
@@ -0,0 +1,199 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+)
+
+// kindEmoji holds the headline emoji for each change event kind
+var kindEmoji = map[string]string{
+	KindInit:         "🌐",
+	KindChange:       "🔄",
+	KindFailover:     "⚠️",
+	KindFailback:     "✅",
+	KindReachability: "🔌",
+}
+
+// timeFormat is used for timestamps in human-readable messages
+const timeFormat = "2006-01-02 15:04:05 MST"
+
+// describeIP formats an address with its label, e.g. "`1.2.3.4` (Office fibre)"
+func describeIP(m markup, ip, label string) string {
+	if label == "" {
+		return m.code(ip)
+	}
+	return m.code(ip) + m.text(" ("+label+")")
+}
+
+// describeStatus formats the current (and previous) address of a family,
+// e.g. "`5.6.7.8` ← `1.2.3.4`"
+func describeStatus(m markup, status IPStatus) string {
+	if status.Current == "" {
+		return m.text("Not available")
+	}
+	if !status.Changed {
+		return describeIP(m, status.Current, status.Label)
+	}
+	if status.Previous == "" {
+		return describeIP(m, status.Current, status.Label) + m.text(" (new)")
+	}
+	return describeIP(m, status.Current, status.Label) + m.text(" ← ") +
+		describeIP(m, status.Previous, status.PreviousLabel)
+}
+
+// formatTitle builds the headline of a change message
+func formatTitle(m markup, event ChangeEvent) string {
+	return m.text(kindEmoji[event.Kind()]+" ") + m.bold(event.Title())
+}
+
+// formatIPSection builds the message line for a single address family
+func formatIPSection(m markup, family string, status IPStatus) string {
+	return m.text("📍 "+family+": ") + describeStatus(m, status)
+}
+
+// formatTransition describes a labelled failover/failback, or returns an empty string
+func formatTransition(m markup, family string, status IPStatus) string {
+	if status.Transition == "" {
+		return ""
+	}
+	from := status.PreviousLabel
+	if from == "" {
+		from = status.Previous
+	}
+	to := status.Label
+	if to == "" {
+		to = status.Current
+	}
+	return m.text("↪️ "+family+" switched from ") + m.bold(from) + m.text(" to ") + m.bold(to)
+}
+
+// formatReverseDNS describes the reverse DNS check of a changed address,
+// or returns an empty string if no check was made
+func formatReverseDNS(m markup, family string, status IPStatus) string {
+	if !status.Changed || (status.PTR == "" && status.RDNSWarning == "") {
+		return ""
+	}
+
+	line := m.text("🔁 " + family + " rDNS: ")
+	if status.PTR != "" {
+		line += m.code(status.PTR)
+		if status.FCrDNS {
+			line += m.text(" (FCrDNS ✅)")
+		}
+	} else {
+		line += m.text("none")
+	}
+	if status.RDNSWarning != "" {
+		line += "\n" + m.text("⚠️ "+status.RDNSWarning)
+	}
+	return line
+}
+
+// formatDNSBL describes the blocklist status of a changed address,
+// or returns an empty string if no check was made. An address is only
+// reported clean if every blocklist answered.
+func formatDNSBL(m markup, family string, status IPStatus) string {
+	if !status.Changed || !status.DNSBLChecked {
+		return ""
+	}
+	unavailable := ""
+	if len(status.DNSBLErrors) > 0 {
+		unavailable = strings.Join(status.DNSBLErrors, ", ") + " unavailable"
+	}
+	answered := status.DNSBLLists - len(status.DNSBLErrors)
+
+	switch {
+	case len(status.DNSBLListed) > 0:
+		line := "🚫 " + family + " DNSBL: listed on " + strings.Join(status.DNSBLListed, ", ")
+		if unavailable != "" {
+			line += " (" + unavailable + ")"
+		}
+		return m.text(line)
+	case answered <= 0:
+		return m.text("⚠️ " + family + " DNSBL: not checked, " + unavailable)
+	case unavailable != "":
+		return m.text(fmt.Sprintf("⚠️ %s DNSBL: clean on %d/%d lists, %s", family, answered, status.DNSBLLists, unavailable))
+	}
+	return m.text("🛡️ " + family + " DNSBL: clean")
+}
+
+// formatPorts lists the reachability of each checked port, or returns an
+// empty string if no ports were checked
+func formatPorts(m markup, family string, status IPStatus) string {
+	if len(status.Ports) == 0 {
+		return ""
+	}
+
+	lines := []string{m.text("🔌 " + family + " ports:")}
+	for _, port := range status.Ports {
+		switch {
+		case port.Inconclusive:
+			lines = append(lines, m.text("    ❔ "+port.Service+" (no response)"))
+		case port.Reachable:
+			lines = append(lines, m.text("    ✅ "+port.Service))
+		default:
+			lines = append(lines, m.text("    ❌ "+port.Service))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// nonEmpty returns the non-empty strings of lines
+func nonEmpty(lines ...string) []string {
+	var result []string
+	for _, line := range lines {
+		if line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// formatTransitions returns the failover/failback summary lines of an event
+func formatTransitions(m markup, event ChangeEvent) []string {
+	return nonEmpty(formatTransition(m, "IPv4", event.IPv4), formatTransition(m, "IPv6", event.IPv6))
+}
+
+// formatDetails returns the per-family check result lines of an event
+func formatDetails(m markup, event ChangeEvent) []string {
+	return nonEmpty(
+		formatReverseDNS(m, "IPv4", event.IPv4), formatDNSBL(m, "IPv4", event.IPv4),
+		formatPorts(m, "IPv4", event.IPv4),
+		formatReverseDNS(m, "IPv6", event.IPv6), formatDNSBL(m, "IPv6", event.IPv6),
+		formatPorts(m, "IPv6", event.IPv6),
+	)
+}
+
+// formatChangeLines returns the body of a change message, below the title
+func formatChangeLines(m markup, event ChangeEvent) []string {
+	lines := formatTransitions(m, event)
+	lines = append(lines,
+		m.text("🖥️ Host: ")+m.code(event.Hostname),
+		formatIPSection(m, "IPv4", event.IPv4),
+		formatIPSection(m, "IPv6", event.IPv6),
+	)
+	lines = append(lines, formatDetails(m, event)...)
+	return append(lines, m.text("🕐 Time: "+event.Timestamp.Format(timeFormat)))
+}
+
+// failureEmoji returns the headline emoji of a failure or recovery event
+func failureEmoji(event FailureEvent) string {
+	if event.Recovered {
+		return "✅"
+	}
+	return "❌"
+}
+
+// formatFailureTitle builds the headline of a failure message
+func formatFailureTitle(m markup, event FailureEvent) string {
+	return m.text(failureEmoji(event)+" ") + m.bold(event.Title())
+}
+
+// formatFailureLines returns the body of a failure message, below the title
+func formatFailureLines(m markup, event FailureEvent) []string {
+	lines := []string{m.text("🖥️ Host: ") + m.code(event.Hostname)}
+	if !event.Recovered {
+		lines = append(lines, m.text("⚠️ Error: "+event.Error))
+	}
+	return append(lines, m.text("🕐 Time: "+event.Timestamp.Format(timeFormat)))
+}
@@ -0,0 +1,48 @@
+package notifier
+
+import "testing"
+
+func TestFormatDNSBL(t *testing.T) {
+	tests := []struct {
+		name   string
+		status IPStatus
+		want   string
+	}{
+		{
+			name:   "not checked",
+			status: IPStatus{Changed: true},
+			want:   "",
+		},
+		{
+			name:   "clean on every list",
+			status: IPStatus{Changed: true, DNSBLChecked: true, DNSBLLists: 2},
+			want:   "🛡️ IPv4 DNSBL: clean",
+		},
+		{
+			name: "clean on some lists",
+			status: IPStatus{Changed: true, DNSBLChecked: true, DNSBLLists: 2,
+				DNSBLErrors: []string{"zen.spamhaus.org"}},
+			want: "⚠️ IPv4 DNSBL: clean on 1/2 lists, zen.spamhaus.org unavailable",
+		},
+		{
+			name: "no list answered",
+			status: IPStatus{Changed: true, DNSBLChecked: true, DNSBLLists: 2,
+				DNSBLErrors: []string{"zen.spamhaus.org", "b.barracudacentral.org"}},
+			want: "⚠️ IPv4 DNSBL: not checked, zen.spamhaus.org, b.barracudacentral.org unavailable",
+		},
+		{
+			name: "listed",
+			status: IPStatus{Changed: true, DNSBLChecked: true, DNSBLLists: 3,
+				DNSBLListed: []string{"bl.spamcop.net"}, DNSBLErrors: []string{"zen.spamhaus.org"}},
+			want: "🚫 IPv4 DNSBL: listed on bl.spamcop.net (zen.spamhaus.org unavailable)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatDNSBL(plainMarkup{}, "IPv4", tt.status); got != tt.want {
+				t.Errorf("formatDNSBL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
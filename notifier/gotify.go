@@ -0,0 +1,114 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// GotifyOptions configure a "gotify" notifier
+type GotifyOptions struct {
+	URL      string `json:"url"`                // server URL, e.g. "https://gotify.example.com"
+	AppToken string `json:"app_token"`          // application token
+	Priority *int   `json:"priority,omitempty"` // 0-10, default 5; 0 shows no notification
+}
+
+// GotifyNotifier pushes messages to a Gotify server
+type GotifyNotifier struct {
+	opts     GotifyOptions
+	priority int
+}
+
+func init() {
+	Register("gotify", func(options json.RawMessage) (Notifier, error) {
+		var opts GotifyOptions
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return NewGotifyNotifier(opts)
+	})
+	RegisterScheme("gotify", "gotify", parseGotifyURL)
+}
+
+// parseGotifyURL parses "gotify://host[:port]/[path/]APP_TOKEN?priority=5"
+func parseGotifyURL(u *url.URL) (interface{}, error) {
+	path := strings.Trim(u.Path, "/")
+	i := strings.LastIndex(path, "/")
+	token := path[i+1:]
+	if u.Host == "" || token == "" {
+		return nil, fmt.Errorf("gotify url needs a host and app token: gotify://host/TOKEN")
+	}
+	server := httpBase(u)
+	if i > 0 {
+		server += "/" + path[:i]
+	}
+	opts := GotifyOptions{URL: server, AppToken: token}
+	if q := u.Query(); q.Has("priority") {
+		priority, err := queryInt(q, "priority")
+		if err != nil {
+			return nil, err
+		}
+		opts.Priority = &priority
+	}
+	return opts, nil
+}
+
+// NewGotifyNotifier creates a Gotify notifier from its options
+func NewGotifyNotifier(opts GotifyOptions) (*GotifyNotifier, error) {
+	if opts.URL == "" || opts.AppToken == "" {
+		return nil, fmt.Errorf("gotify notifier needs url and app_token")
+	}
+	priority := 5
+	if opts.Priority != nil {
+		priority = *opts.Priority
+	}
+	return &GotifyNotifier{opts: opts, priority: priority}, nil
+}
+
+// gotifyMessage is the message creation payload
+type gotifyMessage struct {
+	Title    string                 `json:"title"`
+	Message  string                 `json:"message"`
+	Priority int                    `json:"priority"`
+	Extras   map[string]interface{} `json:"extras,omitempty"`
+}
+
+// SendChange implements Notifier
+func (g *GotifyNotifier) SendChange(event ChangeEvent) error {
+	return g.send(kindEmoji[event.Kind()]+" "+event.Title(), strings.Join(formatChangeLines(commonMarkMarkup{}, event), "  \n"))
+}
+
+// SendTest implements Notifier
+func (g *GotifyNotifier) SendTest(hostname string) error {
+	return g.send("✅ IP Detector Test",
+		"🖥️ Host: "+commonMarkMarkup{}.code(hostname)+"  \nGotify notification is working correctly!")
+}
+
+// SendFailure implements Notifier
+func (g *GotifyNotifier) SendFailure(event FailureEvent) error {
+	return g.send(failureEmoji(event)+" "+event.Title(), strings.Join(formatFailureLines(commonMarkMarkup{}, event), "  \n"))
+}
+
+// send posts a message to the server
+func (g *GotifyNotifier) send(title, message string) error {
+	body, err := json.Marshal(gotifyMessage{
+		Title:    title,
+		Message:  message,
+		Priority: g.priority,
+		Extras: map[string]interface{}{
+			"client::display": map[string]string{"contentType": "text/markdown"},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to serialize gotify message: %w", err)
+	}
+
+	endpoint := strings.TrimRight(g.opts.URL, "/") + "/message"
+	headers := map[string]string{"X-Gotify-Key": g.opts.AppToken}
+	status, respBody, err := doRequest("POST", endpoint, "application/json", body, headers)
+	if err != nil {
+		return fmt.Errorf("failed to send gotify message: %w", err)
+	}
+	return checkStatus("gotify", status, respBody)
+}
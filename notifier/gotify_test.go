@@ -0,0 +1,110 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGotifySendChange(t *testing.T) {
+	srv, requests := newCaptureServer(t, http.StatusOK, `{"id":1}`)
+
+	priority := 8
+	g, err := NewGotifyNotifier(GotifyOptions{URL: srv.URL + "/gotify/", AppToken: "Atoken", Priority: &priority})
+	if err != nil {
+		t.Fatalf("NewGotifyNotifier: %v", err)
+	}
+	if err := g.SendChange(testChangeEvent()); err != nil {
+		t.Fatalf("SendChange: %v", err)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(*requests))
+	}
+	req := (*requests)[0]
+	if req.Method != "POST" || req.Path != "/gotify/message" {
+		t.Errorf("request = %s %s, want POST /gotify/message", req.Method, req.Path)
+	}
+	if got := req.Header.Get("X-Gotify-Key"); got != "Atoken" {
+		t.Errorf("X-Gotify-Key = %q, want Atoken", got)
+	}
+	if got := req.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+
+	var msg struct {
+		Title    string                       `json:"title"`
+		Message  string                       `json:"message"`
+		Priority int                          `json:"priority"`
+		Extras   map[string]map[string]string `json:"extras"`
+	}
+	if err := json.Unmarshal(req.Body, &msg); err != nil {
+		t.Fatalf("invalid body %s: %v", req.Body, err)
+	}
+	if msg.Title != "🔄 IP Address Changed" {
+		t.Errorf("title = %q", msg.Title)
+	}
+	if msg.Priority != 8 {
+		t.Errorf("priority = %d, want 8", msg.Priority)
+	}
+	if !strings.Contains(msg.Message, "`5.6.7.8`") {
+		t.Errorf("message does not contain the new address: %q", msg.Message)
+	}
+	if got := msg.Extras["client::display"]["contentType"]; got != "text/markdown" {
+		t.Errorf("client::display contentType = %q, want text/markdown", got)
+	}
+}
+
+func TestGotifyPriority(t *testing.T) {
+	zero := 0
+	for _, tc := range []struct {
+		name     string
+		priority *int
+		want     int
+	}{
+		{"default", nil, 5},
+		{"explicit zero", &zero, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, requests := newCaptureServer(t, http.StatusOK, `{}`)
+			g, err := NewGotifyNotifier(GotifyOptions{URL: srv.URL, AppToken: "A", Priority: tc.priority})
+			if err != nil {
+				t.Fatalf("NewGotifyNotifier: %v", err)
+			}
+			if err := g.SendTest("vm-1"); err != nil {
+				t.Fatalf("SendTest: %v", err)
+			}
+
+			var msg map[string]interface{}
+			if err := json.Unmarshal((*requests)[0].Body, &msg); err != nil {
+				t.Fatalf("invalid body: %v", err)
+			}
+			if got, ok := msg["priority"].(float64); !ok || int(got) != tc.want {
+				t.Errorf("priority = %v, want %d", msg["priority"], tc.want)
+			}
+		})
+	}
+}
+
+func TestGotifyPriorityOption(t *testing.T) {
+	n, err := New("gotify", json.RawMessage(`{"url":"https://g.example.com","app_token":"A","priority":0}`))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if got := n.(*GotifyNotifier).priority; got != 0 {
+		t.Errorf("priority = %d, want 0", got)
+	}
+}
+
+func TestGotifyErrorStatus(t *testing.T) {
+	srv, _ := newCaptureServer(t, http.StatusUnauthorized, `{"error":"Unauthorized"}`)
+
+	g, err := NewGotifyNotifier(GotifyOptions{URL: srv.URL, AppToken: "bad"})
+	if err != nil {
+		t.Fatalf("NewGotifyNotifier: %v", err)
+	}
+	if err := g.SendTest("vm-1"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("SendTest error = %v, want status 401", err)
+	}
+}
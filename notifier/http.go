@@ -0,0 +1,53 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// httpTimeout bounds every request made by a notifier
+const httpTimeout = 10 * time.Second
+
+// doRequest sends an HTTP request with the given body and headers and returns
+// the status code and (size-limited) response body. Only transport failures
+// are returned as errors; use checkStatus to validate the status code.
+func doRequest(method, url, contentType string, body []byte, headers map[string]string) (int, []byte, error) {
+	return doRequestTimeout(httpTimeout, method, url, contentType, body, headers)
+}
+
+// doRequestTimeout is doRequest with a custom timeout, for long polling
+func doRequestTimeout(timeout time.Duration, method, url, contentType string, body []byte, headers map[string]string) (int, []byte, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "ip_detector/1.0")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	return resp.StatusCode, respBody, nil
+}
+
+// checkStatus returns an error describing a non-2xx response
+func checkStatus(service string, status int, body []byte) error {
+	if status >= 200 && status < 300 {
+		return nil
+	}
+	return fmt.Errorf("%s error (status %d): %s", service, status, string(body))
+}
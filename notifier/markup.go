@@ -0,0 +1,89 @@
+package notifier
+
+import (
+	"html"
+	"strings"
+)
+
+// markup renders message fragments for one output format. Every dynamic or
+// static piece of text goes through it, so that user-controlled values such
+// as hostnames and labels can never break the message syntax.
+type markup interface {
+	// text escapes literal text
+	text(s string) string
+	// bold renders s (unescaped) in bold
+	bold(s string) string
+	// code renders s (unescaped) as inline code
+	code(s string) string
+}
+
+// plainMarkup renders unformatted text
+type plainMarkup struct{}
+
+func (plainMarkup) text(s string) string { return s }
+func (plainMarkup) bold(s string) string { return s }
+func (plainMarkup) code(s string) string { return s }
+
+// htmlMarkup renders HTML, as used by Telegram, Matrix and email
+type htmlMarkup struct{}
+
+func (htmlMarkup) text(s string) string { return html.EscapeString(s) }
+func (htmlMarkup) bold(s string) string { return "<b>" + html.EscapeString(s) + "</b>" }
+func (htmlMarkup) code(s string) string { return "<code>" + html.EscapeString(s) + "</code>" }
+
+// markdownV2Markup renders Telegram MarkdownV2
+type markdownV2Markup struct{}
+
+var (
+	markdownV2Escaper = strings.NewReplacer(
+		`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`,
+		"~", `\~`, "`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`,
+		"|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+	)
+	markdownV2CodeEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`")
+)
+
+func (markdownV2Markup) text(s string) string { return markdownV2Escaper.Replace(s) }
+func (markdownV2Markup) bold(s string) string { return "*" + markdownV2Escaper.Replace(s) + "*" }
+func (markdownV2Markup) code(s string) string { return "`" + markdownV2CodeEscaper.Replace(s) + "`" }
+
+// telegramMarkdownMarkup renders Telegram's legacy Markdown, which can only
+// escape outside of entities; entity delimiters inside entities are dropped
+type telegramMarkdownMarkup struct{}
+
+var telegramMarkdownEscaper = strings.NewReplacer("_", `\_`, "*", `\*`, "`", "\\`", "[", `\[`)
+
+func (telegramMarkdownMarkup) text(s string) string { return telegramMarkdownEscaper.Replace(s) }
+func (telegramMarkdownMarkup) bold(s string) string {
+	return "*" + strings.ReplaceAll(s, "*", "") + "*"
+}
+func (telegramMarkdownMarkup) code(s string) string {
+	return "`" + strings.ReplaceAll(s, "`", "") + "`"
+}
+
+// slackMarkup renders Slack/Mattermost mrkdwn
+type slackMarkup struct{}
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func (slackMarkup) text(s string) string { return slackEscaper.Replace(s) }
+func (slackMarkup) bold(s string) string { return "*" + slackEscaper.Replace(s) + "*" }
+func (slackMarkup) code(s string) string { return "`" + slackEscaper.Replace(s) + "`" }
+
+// commonMarkMarkup renders CommonMark-style Markdown, as used by Discord,
+// ntfy and Gotify
+type commonMarkMarkup struct{}
+
+var commonMarkEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "~", `\~`, "|", `\|`,
+	"[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`,
+)
+
+func (commonMarkMarkup) text(s string) string { return commonMarkEscaper.Replace(s) }
+func (commonMarkMarkup) bold(s string) string { return "**" + commonMarkEscaper.Replace(s) + "**" }
+func (commonMarkMarkup) code(s string) string {
+	if strings.Contains(s, "`") {
+		return "`` " + s + " ``"
+	}
+	return "`" + s + "`"
+}
@@ -0,0 +1,148 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// matrixMaxRetries limits how often a rate-limited or failed event is retried
+const matrixMaxRetries = 3
+
+// MatrixOptions configure a "matrix" notifier
+type MatrixOptions struct {
+	Homeserver  string `json:"homeserver"`        // e.g. "https://matrix.example.com"
+	AccessToken string `json:"access_token"`      // token of the posting (bot) user
+	RoomID      string `json:"room_id"`           // e.g. "!abcdef:example.com"
+	MsgType     string `json:"msgtype,omitempty"` // "m.notice" (default) or "m.text"
+}
+
+// MatrixNotifier posts m.room.message events via the client-server API
+type MatrixNotifier struct {
+	opts MatrixOptions
+}
+
+func init() {
+	Register("matrix", func(options json.RawMessage) (Notifier, error) {
+		var opts MatrixOptions
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return NewMatrixNotifier(opts)
+	})
+	RegisterScheme("matrix", "matrix", parseMatrixURL)
+}
+
+// parseMatrixURL parses "matrix://:TOKEN@homeserver[:port]/?room=!id:server&msgtype=m.text"
+func parseMatrixURL(u *url.URL) (interface{}, error) {
+	q := u.Query()
+	token := urlPassword(u)
+	if u.Host == "" || token == "" || q.Get("room") == "" {
+		return nil, fmt.Errorf("matrix url needs a homeserver, access token and room: matrix://:TOKEN@host/?room=ROOM_ID")
+	}
+	return MatrixOptions{
+		Homeserver:  httpBase(u),
+		AccessToken: token,
+		RoomID:      q.Get("room"),
+		MsgType:     q.Get("msgtype"),
+	}, nil
+}
+
+// NewMatrixNotifier creates a Matrix notifier from its options
+func NewMatrixNotifier(opts MatrixOptions) (*MatrixNotifier, error) {
+	if opts.Homeserver == "" || opts.AccessToken == "" || opts.RoomID == "" {
+		return nil, fmt.Errorf("matrix notifier needs homeserver, access_token and room_id")
+	}
+	if opts.MsgType == "" {
+		opts.MsgType = "m.notice"
+	}
+	return &MatrixNotifier{opts: opts}, nil
+}
+
+// matrixMessage is the content of an m.room.message event
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+// SendChange implements Notifier
+func (m *MatrixNotifier) SendChange(event ChangeEvent) error {
+	return m.send(func(mk markup) []string {
+		return append([]string{formatTitle(mk, event)}, formatChangeLines(mk, event)...)
+	})
+}
+
+// SendTest implements Notifier
+func (m *MatrixNotifier) SendTest(hostname string) error {
+	return m.send(func(mk markup) []string {
+		return []string{
+			mk.text("✅ ") + mk.bold("IP Detector Test"),
+			mk.text("🖥️ Host: ") + mk.code(hostname),
+			mk.text("Matrix notification is working correctly!"),
+		}
+	})
+}
+
+// SendFailure implements Notifier
+func (m *MatrixNotifier) SendFailure(event FailureEvent) error {
+	return m.send(func(mk markup) []string {
+		return append([]string{formatFailureTitle(mk, event)}, formatFailureLines(mk, event)...)
+	})
+}
+
+// send posts a message, built once as plain text and once as HTML, to the
+// room. The transaction ID stays the same across retries so that the
+// homeserver deduplicates a message that was delivered but whose response was
+// lost.
+func (m *MatrixNotifier) send(build func(mk markup) []string) error {
+	body, err := json.Marshal(matrixMessage{
+		MsgType:       m.opts.MsgType,
+		Body:          strings.Join(build(plainMarkup{}), "\n"),
+		Format:        "org.matrix.custom.html",
+		FormattedBody: strings.ReplaceAll(strings.Join(build(htmlMarkup{}), "<br>"), "\n", "<br>"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to serialize matrix message: %w", err)
+	}
+
+	txnID, err := randomID()
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		strings.TrimRight(m.opts.Homeserver, "/"), url.PathEscape(m.opts.RoomID), txnID)
+	headers := map[string]string{"Authorization": "Bearer " + m.opts.AccessToken}
+
+	for attempt := 0; ; attempt++ {
+		status, respBody, err := doRequest("PUT", endpoint, "application/json", body, headers)
+		if err != nil {
+			if attempt >= matrixMaxRetries {
+				return fmt.Errorf("failed to send matrix message: %w", err)
+			}
+			time.Sleep(time.Duration(attempt+1) * time.Second)
+			continue
+		}
+		if status != http.StatusTooManyRequests || attempt >= matrixMaxRetries {
+			return checkStatus("matrix", status, respBody)
+		}
+
+		// M_LIMIT_EXCEEDED carries the wait in milliseconds
+		var rateLimit struct {
+			RetryAfterMs int64 `json:"retry_after_ms"`
+		}
+		_ = json.Unmarshal(respBody, &rateLimit)
+		wait := time.Duration(rateLimit.RetryAfterMs) * time.Millisecond
+		if wait <= 0 {
+			wait = time.Second
+		}
+		if wait > time.Minute {
+			return fmt.Errorf("matrix rate limit exceeded (retry after %s)", wait)
+		}
+		time.Sleep(wait)
+	}
+}
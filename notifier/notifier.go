@@ -0,0 +1,209 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Event kinds, as reported by ChangeEvent.Kind
+const (
+	KindInit         = "init"
+	KindChange       = "change"
+	KindFailover     = "failover"
+	KindFailback     = "failback"
+	KindReachability = "reachability"
+)
+
+// Notifier is implemented by every notification backend
+type Notifier interface {
+	// SendChange reports a change of the public addresses
+	SendChange(event ChangeEvent) error
+	// SendTest sends a test message to verify the configuration
+	SendTest(hostname string) error
+	// SendFailure reports that IP detection failed or recovered
+	SendFailure(event FailureEvent) error
+}
+
+// StatusUpdater is implemented by notifiers that keep a live status message
+// up to date on every check, in addition to notifying changes
+type StatusUpdater interface {
+	// UpdateStatus creates or edits the status message. messages maps each
+	// destination to the ID of its status message and is updated in place
+	// when a new message has to be sent.
+	UpdateStatus(report StatusReport, messages map[string]int64) error
+}
+
+// StatusReport is the state shown in a live status message
+type StatusReport struct {
+	Hostname   string
+	IPv4       IPStatus
+	IPv6       IPStatus
+	LastCheck  time.Time
+	LastChange time.Time // zero if no change was recorded yet
+	Healthy    bool      // false while IP detection is failing
+}
+
+// Factory creates a notifier from its JSON options
+type Factory func(options json.RawMessage) (Notifier, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a notifier type available under the given name
+func Register(kind string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[kind] = factory
+}
+
+// New creates a notifier of the given registered type
+func New(kind string, options json.RawMessage) (Notifier, error) {
+	registryMu.RLock()
+	factory, ok := registry[kind]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown notifier type %q", kind)
+	}
+	return factory(options)
+}
+
+// Types returns the names of all registered notifier types
+func Types() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	kinds := make([]string, 0, len(registry))
+	for kind := range registry {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// decodeOptions unmarshals notifier options, treating missing options as empty
+func decodeOptions(options json.RawMessage, v interface{}) error {
+	if len(options) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(options, v); err != nil {
+		return fmt.Errorf("invalid options: %w", err)
+	}
+	return nil
+}
+
+// Channel is a configured, named notifier instance
+type Channel struct {
+	Name     string
+	Notifier Notifier
+}
+
+// Result holds the delivery outcome for one channel
+type Result struct {
+	Channel string
+	Err     error
+}
+
+// Broadcast calls send for every channel concurrently and returns the
+// delivery results in channel order
+func Broadcast(channels []Channel, send func(Notifier) error) []Result {
+	results := make([]Result, len(channels))
+
+	var wg sync.WaitGroup
+	for i, ch := range channels {
+		wg.Add(1)
+		go func(i int, ch Channel) {
+			defer wg.Done()
+			results[i] = Result{Channel: ch.Name, Err: send(ch.Notifier)}
+		}(i, ch)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// IPStatus holds the status of an IP (current value and whether it changed)
+type IPStatus struct {
+	Current       string
+	Previous      string
+	Changed       bool
+	Service       string   // detection service that reported Current
+	Label         string   // catalogue label of Current, if known
+	PreviousLabel string   // catalogue label of Previous, if known
+	Transition    string   // "failover", "failback" or empty
+	PTR           string   // reverse DNS hostname of Current, if looked up
+	FCrDNS        bool     // PTR hostname resolves back to Current
+	RDNSWarning   string   // problem found by the reverse DNS check, if any (plain text)
+	DNSBLChecked  bool     // a DNS blocklist check was performed
+	DNSBLLists    int      // number of blocklists queried
+	DNSBLListed   []string // blocklists the new address is listed on
+	DNSBLErrors   []string // blocklists that could not be queried
+	Ports         []PortStatus
+}
+
+// PortStatus holds the reachability of one service port on the current address
+type PortStatus struct {
+	Service      string // e.g. "ssh (tcp/22)"
+	Reachable    bool
+	Inconclusive bool
+}
+
+// ChangeEvent describes a change of the public addresses of a host
+type ChangeEvent struct {
+	Hostname  string
+	IPv4      IPStatus
+	IPv6      IPStatus
+	Timestamp time.Time
+	// PortsChanged is set for periodic reachability reports on unchanged addresses
+	PortsChanged bool
+}
+
+// Kind classifies the event as init, change, failover, failback or reachability
+func (e ChangeEvent) Kind() string {
+	switch {
+	case !e.IPv4.Changed && !e.IPv6.Changed && e.PortsChanged:
+		return KindReachability
+	case e.IPv4.Transition == KindFailover || e.IPv6.Transition == KindFailover:
+		return KindFailover
+	case e.IPv4.Transition == KindFailback || e.IPv6.Transition == KindFailback:
+		return KindFailback
+	case (e.IPv4.Changed && e.IPv4.Previous == "") || (e.IPv6.Changed && e.IPv6.Previous == ""):
+		return KindInit
+	}
+	return KindChange
+}
+
+// Title returns a plain-text headline for the event
+func (e ChangeEvent) Title() string {
+	switch e.Kind() {
+	case KindReachability:
+		return "Port Reachability Changed"
+	case KindFailover:
+		return "Failover Detected"
+	case KindFailback:
+		return "Failback Detected"
+	case KindInit:
+		return "IP Detector Initialized"
+	}
+	return "IP Address Changed"
+}
+
+// FailureEvent reports that IP detection failed, or recovered after a failure
+type FailureEvent struct {
+	Hostname  string
+	Error     string
+	Recovered bool
+	Timestamp time.Time
+}
+
+// Title returns a plain-text headline for the event
+func (e FailureEvent) Title() string {
+	if e.Recovered {
+		return "IP Detection Recovered"
+	}
+	return "IP Detection Failed"
+}
@@ -0,0 +1,145 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ntfyTags holds the emoji tag ntfy shows for each event kind
+var ntfyTags = map[string]string{
+	KindInit:         "globe_with_meridians",
+	KindChange:       "arrows_counterclockwise",
+	KindFailover:     "warning",
+	KindFailback:     "white_check_mark",
+	KindReachability: "electric_plug",
+	"failure":        "x",
+	"recovery":       "white_check_mark",
+	"test":           "white_check_mark",
+}
+
+// NtfyOptions configure an "ntfy" notifier
+type NtfyOptions struct {
+	URL         string   `json:"url"`                    // topic URL, e.g. "https://ntfy.example.com/ip-changes"
+	Priority    int      `json:"priority,omitempty"`     // 1 (min) to 5 (max), default 3
+	Tags        []string `json:"tags,omitempty"`         // additional tags/emoji shortcodes
+	AccessToken string   `json:"access_token,omitempty"` // bearer token for protected topics
+}
+
+// NtfyNotifier publishes messages to an ntfy topic
+type NtfyNotifier struct {
+	opts   NtfyOptions
+	server string
+	topic  string
+}
+
+func init() {
+	Register("ntfy", func(options json.RawMessage) (Notifier, error) {
+		var opts NtfyOptions
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return NewNtfyNotifier(opts)
+	})
+	RegisterScheme("ntfy", "ntfy", parseNtfyURL)
+}
+
+// parseNtfyURL parses "ntfy://[:TOKEN@]host[:port]/topic?priority=4&tags=a,b"
+func parseNtfyURL(u *url.URL) (interface{}, error) {
+	topic := strings.Trim(u.Path, "/")
+	if u.Host == "" || topic == "" {
+		return nil, fmt.Errorf("ntfy url needs a host and topic: ntfy://ntfy.sh/TOPIC")
+	}
+	q := u.Query()
+	priority, err := queryInt(q, "priority")
+	if err != nil {
+		return nil, err
+	}
+	return NtfyOptions{
+		URL:         httpBase(u) + "/" + topic,
+		Priority:    priority,
+		Tags:        queryList(q, "tags"),
+		AccessToken: urlPassword(u),
+	}, nil
+}
+
+// NewNtfyNotifier creates an ntfy notifier from its options
+func NewNtfyNotifier(opts NtfyOptions) (*NtfyNotifier, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("ntfy notifier needs a topic url")
+	}
+	if opts.Priority < 0 || opts.Priority > 5 {
+		return nil, fmt.Errorf("ntfy priority must be between 1 and 5")
+	}
+
+	// The JSON publish API takes the topic in the body and is posted to the server root
+	path := strings.Trim(u.Path, "/")
+	idx := strings.LastIndex(path, "/")
+	topic := path[idx+1:]
+	if topic == "" {
+		return nil, fmt.Errorf("ntfy url must include a topic")
+	}
+	u.Path = "/" + path[:idx+1]
+	u.RawQuery = ""
+
+	return &NtfyNotifier{opts: opts, server: u.String(), topic: topic}, nil
+}
+
+// ntfyMessage is the JSON publish payload
+type ntfyMessage struct {
+	Topic    string   `json:"topic"`
+	Title    string   `json:"title"`
+	Message  string   `json:"message"`
+	Priority int      `json:"priority,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Markdown bool     `json:"markdown"`
+}
+
+// SendChange implements Notifier
+func (n *NtfyNotifier) SendChange(event ChangeEvent) error {
+	return n.send(event.Kind(), event.Title(), strings.Join(formatChangeLines(commonMarkMarkup{}, event), "\n"))
+}
+
+// SendTest implements Notifier
+func (n *NtfyNotifier) SendTest(hostname string) error {
+	return n.send("test", "IP Detector Test",
+		"🖥️ Host: "+commonMarkMarkup{}.code(hostname)+"\nntfy notification is working correctly!")
+}
+
+// SendFailure implements Notifier
+func (n *NtfyNotifier) SendFailure(event FailureEvent) error {
+	kind := "failure"
+	if event.Recovered {
+		kind = "recovery"
+	}
+	return n.send(kind, event.Title(), strings.Join(formatFailureLines(commonMarkMarkup{}, event), "\n"))
+}
+
+// send publishes a message to the topic
+func (n *NtfyNotifier) send(kind, title, message string) error {
+	tags := append([]string{ntfyTags[kind]}, n.opts.Tags...)
+	body, err := json.Marshal(ntfyMessage{
+		Topic:    n.topic,
+		Title:    title,
+		Message:  message,
+		Priority: n.opts.Priority,
+		Tags:     tags,
+		Markdown: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to serialize ntfy message: %w", err)
+	}
+
+	headers := map[string]string{}
+	if n.opts.AccessToken != "" {
+		headers["Authorization"] = "Bearer " + n.opts.AccessToken
+	}
+
+	status, respBody, err := doRequest("POST", n.server, "application/json", body, headers)
+	if err != nil {
+		return fmt.Errorf("failed to send ntfy message: %w", err)
+	}
+	return checkStatus("ntfy", status, respBody)
+}
@@ -0,0 +1,143 @@
+package notifier
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// capturedRequest is a request received by a test server
+type capturedRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// newCaptureServer starts a server that records every request and answers
+// with status and body
+func newCaptureServer(t *testing.T, status int, body string) (*httptest.Server, *[]capturedRequest) {
+	t.Helper()
+	var requests []capturedRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		requests = append(requests, capturedRequest{Method: r.Method, Path: r.URL.RequestURI(), Header: r.Header.Clone(), Body: data})
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func testChangeEvent() ChangeEvent {
+	return ChangeEvent{
+		Hostname:  "vm-1",
+		IPv4:      IPStatus{Current: "5.6.7.8", Previous: "1.2.3.4", Changed: true},
+		IPv6:      IPStatus{Current: "2001:db8::1"},
+		Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func TestNtfySendChange(t *testing.T) {
+	srv, requests := newCaptureServer(t, http.StatusOK, `{"id":"x"}`)
+
+	n, err := NewNtfyNotifier(NtfyOptions{
+		URL:         srv.URL + "/sub/ip-changes",
+		Priority:    4,
+		Tags:        []string{"server"},
+		AccessToken: "tk_secret",
+	})
+	if err != nil {
+		t.Fatalf("NewNtfyNotifier: %v", err)
+	}
+	if err := n.SendChange(testChangeEvent()); err != nil {
+		t.Fatalf("SendChange: %v", err)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(*requests))
+	}
+	req := (*requests)[0]
+	if req.Method != "POST" || req.Path != "/sub/" {
+		t.Errorf("request = %s %s, want POST /sub/", req.Method, req.Path)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer tk_secret" {
+		t.Errorf("Authorization = %q, want %q", got, "Bearer tk_secret")
+	}
+	if got := req.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+
+	var msg ntfyMessage
+	if err := json.Unmarshal(req.Body, &msg); err != nil {
+		t.Fatalf("invalid body %s: %v", req.Body, err)
+	}
+	if msg.Topic != "ip-changes" {
+		t.Errorf("topic = %q, want ip-changes", msg.Topic)
+	}
+	if msg.Priority != 4 {
+		t.Errorf("priority = %d, want 4", msg.Priority)
+	}
+	if want := []string{ntfyTags[KindChange], "server"}; strings.Join(msg.Tags, ",") != strings.Join(want, ",") {
+		t.Errorf("tags = %v, want %v", msg.Tags, want)
+	}
+	if msg.Title != "IP Address Changed" || !msg.Markdown {
+		t.Errorf("title = %q, markdown = %t", msg.Title, msg.Markdown)
+	}
+	if !strings.Contains(msg.Message, "`5.6.7.8`") || !strings.Contains(msg.Message, "`1.2.3.4`") {
+		t.Errorf("message does not contain the addresses: %q", msg.Message)
+	}
+}
+
+func TestNtfyNoAccessToken(t *testing.T) {
+	srv, requests := newCaptureServer(t, http.StatusOK, `{}`)
+
+	n, err := NewNtfyNotifier(NtfyOptions{URL: srv.URL + "/topic"})
+	if err != nil {
+		t.Fatalf("NewNtfyNotifier: %v", err)
+	}
+	if err := n.SendTest("vm-1"); err != nil {
+		t.Fatalf("SendTest: %v", err)
+	}
+
+	req := (*requests)[0]
+	if got := req.Header.Get("Authorization"); got != "" {
+		t.Errorf("Authorization = %q, want none", got)
+	}
+	var msg map[string]interface{}
+	if err := json.Unmarshal(req.Body, &msg); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if _, ok := msg["priority"]; ok {
+		t.Errorf("priority sent without being configured: %v", msg["priority"])
+	}
+}
+
+func TestNtfyErrorStatus(t *testing.T) {
+	srv, _ := newCaptureServer(t, http.StatusForbidden, `{"error":"forbidden"}`)
+
+	n, err := NewNtfyNotifier(NtfyOptions{URL: srv.URL + "/topic"})
+	if err != nil {
+		t.Fatalf("NewNtfyNotifier: %v", err)
+	}
+	err = n.SendTest("vm-1")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("SendTest error = %v, want status 403", err)
+	}
+}
+
+func TestNewNtfyNotifierInvalid(t *testing.T) {
+	for _, opts := range []NtfyOptions{
+		{URL: ""},
+		{URL: "https://ntfy.sh/"},
+		{URL: "https://ntfy.sh/topic", Priority: 6},
+	} {
+		if _, err := NewNtfyNotifier(opts); err == nil {
+			t.Errorf("NewNtfyNotifier(%+v) succeeded, want error", opts)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package notifier
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// signalTimeout bounds a send request; signal-cli in its default "normal"
+// mode starts a JVM per request, which regularly takes longer than
+// httpTimeout
+const signalTimeout = 30 * time.Second
+
+// SignalOptions configure a "signal" notifier
+type SignalOptions struct {
+	URL        string   `json:"url"`                  // signal-cli REST API, e.g. "http://localhost:8080"
+	Number     string   `json:"number"`               // registered sender number, e.g. "+4912345678"
+	Recipients []string `json:"recipients,omitempty"` // phone numbers or usernames
+	Groups     []string `json:"groups,omitempty"`     // "group.<id>" from /v1/groups, or the group's internal_id
+}
+
+// SignalNotifier sends messages through a signal-cli REST API server
+type SignalNotifier struct {
+	opts       SignalOptions
+	recipients []string
+}
+
+func init() {
+	Register("signal", func(options json.RawMessage) (Notifier, error) {
+		var opts SignalOptions
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return NewSignalNotifier(opts)
+	})
+	RegisterScheme("signal", "signal", parseSignalURL)
+}
+
+// parseSignalURL parses "signal://host[:port]/+SENDER/+RECIPIENT/...?groups=ID,...&scheme=http"
+func parseSignalURL(u *url.URL) (interface{}, error) {
+	var parts []string
+	for _, part := range strings.Split(u.Path, "/") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	q := u.Query()
+	if u.Host == "" || len(parts) == 0 {
+		return nil, fmt.Errorf("signal url needs a host and sender number: signal://host:8080/+SENDER/+RECIPIENT")
+	}
+	return SignalOptions{
+		URL:        httpBase(u),
+		Number:     parts[0],
+		Recipients: parts[1:],
+		Groups:     queryList(q, "groups"),
+	}, nil
+}
+
+// NewSignalNotifier creates a Signal notifier from its options
+func NewSignalNotifier(opts SignalOptions) (*SignalNotifier, error) {
+	if opts.URL == "" || opts.Number == "" {
+		return nil, fmt.Errorf("signal notifier needs url and number")
+	}
+	if len(opts.Recipients) == 0 && len(opts.Groups) == 0 {
+		return nil, fmt.Errorf("signal notifier needs recipients or groups")
+	}
+
+	recipients := append([]string(nil), opts.Recipients...)
+	for _, group := range opts.Groups {
+		recipients = append(recipients, signalGroupID(group))
+	}
+	return &SignalNotifier{opts: opts, recipients: recipients}, nil
+}
+
+// signalGroupID returns the "group.<base64>" form the REST API expects for a
+// group, which is the base64 encoding of the internal_id it also reports
+func signalGroupID(group string) string {
+	if strings.HasPrefix(group, "group.") {
+		return group
+	}
+	return "group." + base64.StdEncoding.EncodeToString([]byte(group))
+}
+
+// signalMessage is the /v2/send payload
+type signalMessage struct {
+	Message    string   `json:"message"`
+	Number     string   `json:"number"`
+	Recipients []string `json:"recipients"`
+}
+
+// SendChange implements Notifier
+func (s *SignalNotifier) SendChange(event ChangeEvent) error {
+	return s.send(append([]string{formatTitle(plainMarkup{}, event)}, formatChangeLines(plainMarkup{}, event)...))
+}
+
+// SendTest implements Notifier
+func (s *SignalNotifier) SendTest(hostname string) error {
+	return s.send([]string{
+		"✅ IP Detector Test",
+		"🖥️ Host: " + hostname,
+		"Signal notification is working correctly!",
+	})
+}
+
+// SendFailure implements Notifier
+func (s *SignalNotifier) SendFailure(event FailureEvent) error {
+	return s.send(append([]string{formatFailureTitle(plainMarkup{}, event)}, formatFailureLines(plainMarkup{}, event)...))
+}
+
+// send delivers a plain-text message to all recipients and groups
+func (s *SignalNotifier) send(lines []string) error {
+	body, err := json.Marshal(signalMessage{
+		Message:    strings.Join(lines, "\n"),
+		Number:     s.opts.Number,
+		Recipients: s.recipients,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to serialize signal message: %w", err)
+	}
+
+	endpoint := strings.TrimRight(s.opts.URL, "/") + "/v2/send"
+	status, respBody, err := doRequestTimeout(signalTimeout, "POST", endpoint, "application/json", body, nil)
+	if err != nil {
+		return fmt.Errorf("failed to send signal message: %w", err)
+	}
+	return checkStatus("signal", status, respBody)
+}
@@ -0,0 +1,193 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// SlackOptions configure a "slack" notifier. Mattermost's Slack-compatible
+// incoming webhooks are supported as well.
+type SlackOptions struct {
+	WebhookURL string `json:"webhook_url"`
+	Channel    string `json:"channel,omitempty"`    // override the webhook's default channel
+	Username   string `json:"username,omitempty"`   // override the webhook's display name
+	IconEmoji  string `json:"icon_emoji,omitempty"` // e.g. ":globe_with_meridians:"
+}
+
+// SlackNotifier posts Block Kit messages to a Slack or Mattermost incoming webhook
+type SlackNotifier struct {
+	opts SlackOptions
+}
+
+func init() {
+	Register("slack", func(options json.RawMessage) (Notifier, error) {
+		var opts SlackOptions
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return NewSlackNotifier(opts)
+	})
+	// Mattermost accepts the same payload on its incoming webhooks
+	Register("mattermost", func(options json.RawMessage) (Notifier, error) {
+		var opts SlackOptions
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return NewSlackNotifier(opts)
+	})
+	RegisterScheme("slack", "slack", parseSlackURL)
+	RegisterScheme("mattermost", "mattermost", parseMattermostURL)
+}
+
+// slackURLOptions returns the query options shared by Slack and Mattermost URLs
+func slackURLOptions(webhookURL string, q url.Values) SlackOptions {
+	return SlackOptions{
+		WebhookURL: webhookURL,
+		Channel:    q.Get("channel"),
+		Username:   q.Get("username"),
+		IconEmoji:  q.Get("icon_emoji"),
+	}
+}
+
+// parseSlackURL parses "slack://T000/B000/XXXX?channel=...&username=...&icon_emoji=..."
+// with the three parts of the incoming webhook URL
+func parseSlackURL(u *url.URL) (interface{}, error) {
+	parts := strings.Split(strings.Trim(u.Host+u.Path, "/"), "/")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("slack url needs the webhook path: slack://T000/B000/XXXX")
+	}
+	return slackURLOptions("https://hooks.slack.com/services/"+strings.Join(parts, "/"), u.Query()), nil
+}
+
+// parseMattermostURL parses "mattermost://host[:port]/TOKEN?channel=..."
+func parseMattermostURL(u *url.URL) (interface{}, error) {
+	token := strings.Trim(u.Path, "/")
+	if u.Host == "" || token == "" || strings.Contains(token, "/") {
+		return nil, fmt.Errorf("mattermost url needs a host and webhook token: mattermost://host/TOKEN")
+	}
+	q := u.Query()
+	opts := slackURLOptions(httpBase(u)+"/hooks/"+token, q)
+	if opts.Username == "" {
+		opts.Username = urlUsername(u)
+	}
+	return opts, nil
+}
+
+// NewSlackNotifier creates a Slack notifier from its options
+func NewSlackNotifier(opts SlackOptions) (*SlackNotifier, error) {
+	if opts.WebhookURL == "" {
+		return nil, fmt.Errorf("slack notifier needs a webhook_url")
+	}
+	return &SlackNotifier{opts: opts}, nil
+}
+
+// slackText is a Block Kit text object
+type slackText struct {
+	Type  string `json:"type"` // "plain_text" or "mrkdwn"
+	Text  string `json:"text"`
+	Emoji bool   `json:"emoji,omitempty"`
+}
+
+// slackBlock is a Block Kit layout block
+type slackBlock struct {
+	Type   string      `json:"type"`
+	Text   *slackText  `json:"text,omitempty"`
+	Fields []slackText `json:"fields,omitempty"`
+}
+
+// slackMessage is the incoming webhook payload; Text is the notification
+// fallback and is what Mattermost renders
+type slackMessage struct {
+	Text      string       `json:"text"`
+	Blocks    []slackBlock `json:"blocks,omitempty"`
+	Channel   string       `json:"channel,omitempty"`
+	Username  string       `json:"username,omitempty"`
+	IconEmoji string       `json:"icon_emoji,omitempty"`
+}
+
+// mrkdwn creates a Block Kit mrkdwn text object from already escaped text
+func mrkdwn(text string) slackText {
+	return slackText{Type: "mrkdwn", Text: text}
+}
+
+// header creates a Block Kit header block
+func header(title string) slackBlock {
+	return slackBlock{Type: "header", Text: &slackText{Type: "plain_text", Text: title, Emoji: true}}
+}
+
+// section creates a Block Kit section block with mrkdwn text
+func section(text string) slackBlock {
+	t := mrkdwn(text)
+	return slackBlock{Type: "section", Text: &t}
+}
+
+// SendChange implements Notifier
+func (s *SlackNotifier) SendChange(event ChangeEvent) error {
+	m := slackMarkup{}
+	title := kindEmoji[event.Kind()] + " " + event.Title()
+	timestamp := event.Timestamp.Format(timeFormat)
+
+	blocks := []slackBlock{header(title)}
+	if lines := formatTransitions(m, event); len(lines) > 0 {
+		blocks = append(blocks, section(strings.Join(lines, "\n")))
+	}
+	blocks = append(blocks, slackBlock{
+		Type: "section",
+		Fields: []slackText{
+			mrkdwn(m.bold("Host") + "\n" + m.code(event.Hostname)),
+			mrkdwn(m.bold("Time") + "\n" + m.text(timestamp)),
+			mrkdwn(m.bold("IPv4") + "\n" + describeStatus(m, event.IPv4)),
+			mrkdwn(m.bold("IPv6") + "\n" + describeStatus(m, event.IPv6)),
+		},
+	})
+	if lines := formatDetails(m, event); len(lines) > 0 {
+		blocks = append(blocks, section(strings.Join(lines, "\n")))
+	}
+
+	// Fallback for notifications and Mattermost
+	text := m.text(title) + "\n" + strings.Join(formatChangeLines(m, event), "\n")
+	return s.send(text, blocks)
+}
+
+// SendTest implements Notifier
+func (s *SlackNotifier) SendTest(hostname string) error {
+	m := slackMarkup{}
+	text := "✅ IP Detector Test\nHost: " + m.code(hostname) + "\nSlack notification is working correctly!"
+	return s.send(text, []slackBlock{
+		header("✅ IP Detector Test"),
+		section("🖥️ Host: " + m.code(hostname) + "\nSlack notification is working correctly!"),
+	})
+}
+
+// SendFailure implements Notifier
+func (s *SlackNotifier) SendFailure(event FailureEvent) error {
+	m := slackMarkup{}
+	title := failureEmoji(event) + " " + event.Title()
+	body := strings.Join(formatFailureLines(m, event), "\n")
+	return s.send(m.text(title)+"\n"+body, []slackBlock{
+		header(title),
+		section(body),
+	})
+}
+
+// send posts a message to the webhook
+func (s *SlackNotifier) send(text string, blocks []slackBlock) error {
+	body, err := json.Marshal(slackMessage{
+		Text:      text,
+		Blocks:    blocks,
+		Channel:   s.opts.Channel,
+		Username:  s.opts.Username,
+		IconEmoji: s.opts.IconEmoji,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to serialize slack message: %w", err)
+	}
+
+	status, respBody, err := doRequest("POST", s.opts.WebhookURL, "application/json", body, nil)
+	if err != nil {
+		return fmt.Errorf("failed to send slack message: %w", err)
+	}
+	return checkStatus("slack", status, respBody)
+}
@@ -0,0 +1,226 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Adaptive Card text colours
+const (
+	teamsAccent    = "Accent"
+	teamsGood      = "Good"
+	teamsWarning   = "Warning"
+	teamsAttention = "Attention"
+)
+
+// teamsColors maps change event kinds to title colours
+var teamsColors = map[string]string{
+	KindInit:         teamsAccent,
+	KindChange:       teamsWarning,
+	KindFailover:     teamsAttention,
+	KindFailback:     teamsGood,
+	KindReachability: teamsAccent,
+}
+
+// TeamsOptions configure a "teams" notifier
+type TeamsOptions struct {
+	WebhookURL string `json:"webhook_url"` // Workflows "When a Teams webhook request is received" URL or incoming webhook
+}
+
+// TeamsNotifier posts Adaptive Cards to a Microsoft Teams webhook
+type TeamsNotifier struct {
+	opts TeamsOptions
+}
+
+func init() {
+	Register("teams", func(options json.RawMessage) (Notifier, error) {
+		var opts TeamsOptions
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return NewTeamsNotifier(opts)
+	})
+	RegisterScheme("teams", "teams", parseTeamsURL)
+}
+
+// parseTeamsURL parses "teams://host/path?query", the webhook URL with its
+// "https" scheme replaced
+func parseTeamsURL(u *url.URL) (interface{}, error) {
+	if u.Host == "" || u.Path == "" {
+		return nil, fmt.Errorf("teams url needs the webhook host and path: teams://host/path?query")
+	}
+	q := u.Query()
+	q.Del("scheme")
+	webhook := url.URL{Scheme: "https", Host: u.Host, Path: u.Path, RawPath: u.RawPath, RawQuery: q.Encode()}
+	if strings.HasPrefix(httpBase(u), "http:") {
+		webhook.Scheme = "http"
+	}
+	return TeamsOptions{WebhookURL: webhook.String()}, nil
+}
+
+// NewTeamsNotifier creates a Teams notifier from its options
+func NewTeamsNotifier(opts TeamsOptions) (*TeamsNotifier, error) {
+	if opts.WebhookURL == "" {
+		return nil, fmt.Errorf("teams notifier needs a webhook_url")
+	}
+	return &TeamsNotifier{opts: opts}, nil
+}
+
+// teamsElement is an Adaptive Card TextBlock or FactSet
+type teamsElement struct {
+	Type    string      `json:"type"`
+	Text    string      `json:"text,omitempty"`
+	Size    string      `json:"size,omitempty"`
+	Weight  string      `json:"weight,omitempty"`
+	Color   string      `json:"color,omitempty"`
+	Wrap    bool        `json:"wrap,omitempty"`
+	Spacing string      `json:"spacing,omitempty"`
+	Facts   []teamsFact `json:"facts,omitempty"`
+}
+
+// teamsFact is a single title/value pair in a FactSet
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// teamsCard is an Adaptive Card
+type teamsCard struct {
+	Schema  string         `json:"$schema"`
+	Type    string         `json:"type"`
+	Version string         `json:"version"`
+	Body    []teamsElement `json:"body"`
+	MSTeams struct {
+		Width string `json:"width"`
+	} `json:"msteams"`
+}
+
+// teamsAttachment wraps a card in a message
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+// teamsMessage is the webhook payload
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+// teamsTitle returns the headline TextBlock of a card
+func teamsTitle(text, color string) teamsElement {
+	return teamsElement{Type: "TextBlock", Text: text, Size: "Large", Weight: "Bolder", Color: color, Wrap: true}
+}
+
+// teamsText returns a TextBlock per line of lines
+func teamsText(lines []string) []teamsElement {
+	var elements []teamsElement
+	for _, line := range lines {
+		for _, text := range strings.Split(line, "\n") {
+			elements = append(elements, teamsElement{Type: "TextBlock", Text: text, Wrap: true, Spacing: "Small"})
+		}
+	}
+	return elements
+}
+
+// teamsIPFacts lists the old and new address of a changed family, or just
+// the current one
+func teamsIPFacts(family string, status IPStatus) []teamsFact {
+	m := plainMarkup{}
+	if !status.Changed {
+		return []teamsFact{{Title: family, Value: describeStatus(m, status)}}
+	}
+	old := "None"
+	if status.Previous != "" {
+		old = describeIP(m, status.Previous, status.PreviousLabel)
+	}
+	return []teamsFact{
+		{Title: "Old " + family, Value: old},
+		{Title: "New " + family, Value: describeIP(m, status.Current, status.Label)},
+	}
+}
+
+// SendChange implements Notifier
+func (t *TeamsNotifier) SendChange(event ChangeEvent) error {
+	m := plainMarkup{}
+	kind := event.Kind()
+
+	facts := []teamsFact{{Title: "Host", Value: event.Hostname}}
+	facts = append(facts, teamsIPFacts("IPv4", event.IPv4)...)
+	facts = append(facts, teamsIPFacts("IPv6", event.IPv6)...)
+	facts = append(facts, teamsFact{Title: "Time", Value: event.Timestamp.Format(timeFormat)})
+
+	body := []teamsElement{teamsTitle(kindEmoji[kind]+" "+event.Title(), teamsColors[kind])}
+	body = append(body, teamsText(formatTransitions(m, event))...)
+	body = append(body, teamsElement{Type: "FactSet", Facts: facts})
+	body = append(body, teamsText(formatDetails(m, event))...)
+	return t.send(body)
+}
+
+// SendTest implements Notifier
+func (t *TeamsNotifier) SendTest(hostname string) error {
+	return t.send([]teamsElement{
+		teamsTitle("✅ IP Detector Test", teamsGood),
+		{Type: "TextBlock", Text: "Teams notification is working correctly!", Wrap: true},
+		{Type: "FactSet", Facts: []teamsFact{
+			{Title: "Host", Value: hostname},
+			{Title: "Time", Value: time.Now().Format(timeFormat)},
+		}},
+	})
+}
+
+// SendFailure implements Notifier
+func (t *TeamsNotifier) SendFailure(event FailureEvent) error {
+	color := teamsAttention
+	if event.Recovered {
+		color = teamsGood
+	}
+	facts := []teamsFact{{Title: "Host", Value: event.Hostname}}
+	if !event.Recovered {
+		facts = append(facts, teamsFact{Title: "Error", Value: event.Error})
+	}
+	facts = append(facts, teamsFact{Title: "Time", Value: event.Timestamp.Format(timeFormat)})
+
+	return t.send([]teamsElement{
+		teamsTitle(failureEmoji(event)+" "+event.Title(), color),
+		{Type: "FactSet", Facts: facts},
+	})
+}
+
+// send posts an Adaptive Card with the given body to the webhook
+func (t *TeamsNotifier) send(body []teamsElement) error {
+	card := teamsCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		Body:    body,
+	}
+	card.MSTeams.Width = "Full"
+
+	payload, err := json.Marshal(teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content:     card,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to serialize teams message: %w", err)
+	}
+
+	status, respBody, err := doRequest("POST", t.opts.WebhookURL, "application/json", payload, nil)
+	if err != nil {
+		return fmt.Errorf("failed to send teams message: %w", err)
+	}
+	if err := checkStatus("teams", status, respBody); err != nil {
+		return err
+	}
+	// Legacy incoming webhooks report some failures with a 200 status
+	if strings.Contains(string(respBody), "delivery failed") {
+		return fmt.Errorf("teams error: %s", string(respBody))
+	}
+	return nil
+}
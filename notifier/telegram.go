@@ -1,115 +1,425 @@
 package notifier
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
+
+	"ip_detector/storage"
+)
+
+// DefaultTelegramAPIURL is the base URL of the public Bot API
+const DefaultTelegramAPIURL = "https://api.telegram.org"
+
+// Telegram parse modes
+const (
+	ParseModeHTML       = "HTML"
+	ParseModeMarkdownV2 = "MarkdownV2"
+	ParseModeMarkdown   = "Markdown" // legacy, cannot escape inside entities
 )
 
+// Event filters of a Telegram destination, in addition to the change event
+// kinds (KindInit, KindFailover, ...)
+const (
+	TelegramEventChange   = "change"   // every change event
+	TelegramEventFailure  = "failure"  // IP detection failed
+	TelegramEventRecovery = "recovery" // IP detection works again
+	TelegramEventStatus   = "status"   // the pinned status message
+	TelegramEventChart    = "chart"    // the scheduled history chart
+)
+
+// telegramEvents holds the valid destination event filters
+var telegramEvents = map[string]bool{
+	TelegramEventChange: true, TelegramEventFailure: true, TelegramEventRecovery: true,
+	TelegramEventStatus: true, TelegramEventChart: true, KindInit: true, KindFailover: true, KindFailback: true,
+	KindReachability: true,
+}
+
+// TelegramDestination is a chat, or a topic in a forum chat, that messages are sent to
+type TelegramDestination struct {
+	ChatID              string   `json:"chat_id"`                        // may be encrypted ("enc:...")
+	MessageThreadID     int64    `json:"message_thread_id,omitempty"`    // forum topic
+	DisableNotification bool     `json:"disable_notification,omitempty"` // deliver silently
+	Events              []string `json:"events,omitempty"`               // event filter, default all
+}
+
+// accepts reports whether the destination receives an event known by any of
+// the given names; test messages (no names) go to every destination
+func (d TelegramDestination) accepts(names ...string) bool {
+	if len(d.Events) == 0 || len(names) == 0 {
+		return true
+	}
+	for _, event := range d.Events {
+		for _, name := range names {
+			if event == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// key identifies the destination, e.g. to remember its status message. It is
+// an HMAC keyed with the storage key, so the plaintext state does not reveal
+// chat IDs, which are short enough to be recovered from a plain hash.
+func (d TelegramDestination) key() (string, error) {
+	fingerprint, err := storage.Fingerprint(d.legacyKey())
+	if err != nil {
+		return "", fmt.Errorf("failed to derive chat key: %w", err)
+	}
+	return "hmac:" + fingerprint[:32], nil
+}
+
+// legacyKey is the plaintext "chat" or "chat/topic" key used by earlier
+// versions, so that their status messages can be taken over
+func (d TelegramDestination) legacyKey() string {
+	if d.MessageThreadID != 0 {
+		return d.ChatID + "/" + strconv.FormatInt(d.MessageThreadID, 10)
+	}
+	return d.ChatID
+}
+
+// sendParams returns the destination-specific parameters of sendMessage
+func (d TelegramDestination) sendParams() url.Values {
+	params := url.Values{}
+	if d.MessageThreadID != 0 {
+		params.Set("message_thread_id", strconv.FormatInt(d.MessageThreadID, 10))
+	}
+	if d.DisableNotification {
+		params.Set("disable_notification", "true")
+	}
+	return params
+}
+
 // TelegramNotifier handles sending notifications via Telegram
 type TelegramNotifier struct {
-	BotToken string
-	ChatID   string
+	BotToken  string
+	APIURL    string // Bot API base URL, e.g. a self-hosted telegram-bot-api server
+	Chats     []TelegramDestination
+	ParseMode string // one of the ParseMode constants; empty sends plain text
+	// StatusMessage keeps a pinned status message updated on every check
+	StatusMessage bool
+}
+
+// telegramOptions are the JSON options of a "telegram" notifier
+type telegramOptions struct {
+	BotToken      string                `json:"bot_token"`
+	APIURL        string                `json:"api_url,omitempty"` // default DefaultTelegramAPIURL
+	ChatID        string                `json:"chat_id,omitempty"` // single chat receiving everything
+	Chats         []TelegramDestination `json:"chats,omitempty"`
+	ParseMode     string                `json:"parse_mode,omitempty"` // default "HTML"
+	StatusMessage bool                  `json:"status_message,omitempty"`
+}
+
+func init() {
+	Register("telegram", func(options json.RawMessage) (Notifier, error) {
+		var opts telegramOptions
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		if opts.BotToken == "" || (opts.ChatID == "" && len(opts.Chats) == 0) {
+			return nil, fmt.Errorf("telegram notifier needs bot_token and chat_id or chats")
+		}
+
+		t := NewTelegramNotifier(opts.BotToken, opts.ChatID)
+		if opts.ChatID == "" {
+			t.Chats = nil
+		}
+		for i, chat := range opts.Chats {
+			if chat.ChatID == "" {
+				return nil, fmt.Errorf("telegram chat %d needs a chat_id", i+1)
+			}
+			for _, event := range chat.Events {
+				if !telegramEvents[event] {
+					return nil, fmt.Errorf("telegram chat %d: unknown event %q", i+1, event)
+				}
+			}
+			t.Chats = append(t.Chats, chat)
+		}
+		if opts.ParseMode != "" {
+			t.ParseMode = opts.ParseMode
+		}
+		if opts.APIURL != "" {
+			if err := ValidateTelegramAPIURL(opts.APIURL); err != nil {
+				return nil, err
+			}
+			t.APIURL = opts.APIURL
+		}
+		t.StatusMessage = opts.StatusMessage
+		if _, err := t.markup(); err != nil {
+			return nil, err
+		}
+		return t, nil
+	})
+	RegisterScheme("telegram", "telegram", parseTelegramURL)
+}
+
+// parseTelegramURL parses
+// "telegram://TOKEN@telegram?chats=CHAT[:TOPIC],...&notification=no&parse_mode=...&status_message=yes".
+// A host other than "telegram" is used as the Bot API server.
+func parseTelegramURL(u *url.URL) (interface{}, error) {
+	token := urlUsername(u)
+	if password := urlPassword(u); password != "" {
+		token += ":" + password
+	}
+	q := u.Query()
+	if token == "" || len(queryList(q, "chats")) == 0 {
+		return nil, fmt.Errorf("telegram url needs a bot token and chats: telegram://TOKEN@telegram?chats=CHAT_ID")
+	}
+
+	notification, err := queryBool(q, "notification", true)
+	if err != nil {
+		return nil, err
+	}
+	statusMessage, err := queryBool(q, "status_message", false)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := telegramOptions{
+		BotToken:      token,
+		ParseMode:     q.Get("parse_mode"),
+		StatusMessage: statusMessage,
+	}
+	if !strings.EqualFold(u.Hostname(), "telegram") {
+		opts.APIURL = httpBase(u)
+	}
+	for _, chat := range queryList(q, "chats") {
+		dest := TelegramDestination{ChatID: chat, DisableNotification: !notification}
+		if id, topic, ok := strings.Cut(chat, ":"); ok {
+			thread, err := strconv.ParseInt(topic, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid telegram topic in chats: expected CHAT_ID:TOPIC_ID")
+			}
+			dest.ChatID, dest.MessageThreadID = id, thread
+		}
+		opts.Chats = append(opts.Chats, dest)
+	}
+	return opts, nil
 }
 
-// NewTelegramNotifier creates a new TelegramNotifier
+// NewTelegramNotifier creates a new TelegramNotifier sending HTML messages to a single chat
 func NewTelegramNotifier(botToken, chatID string) *TelegramNotifier {
 	return &TelegramNotifier{
-		BotToken: botToken,
-		ChatID:   chatID,
+		BotToken:  botToken,
+		APIURL:    DefaultTelegramAPIURL,
+		Chats:     []TelegramDestination{{ChatID: chatID}},
+		ParseMode: ParseModeHTML,
 	}
 }
 
-// SendMessage sends a message via Telegram Bot API
-func (t *TelegramNotifier) SendMessage(message string) error {
-	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.BotToken)
+// markup returns the formatter for the configured parse mode
+func (t *TelegramNotifier) markup() (markup, error) {
+	switch t.ParseMode {
+	case ParseModeHTML:
+		return htmlMarkup{}, nil
+	case ParseModeMarkdownV2:
+		return markdownV2Markup{}, nil
+	case ParseModeMarkdown:
+		return telegramMarkdownMarkup{}, nil
+	case "":
+		return plainMarkup{}, nil
+	}
+	return nil, fmt.Errorf("unsupported telegram parse_mode %q", t.ParseMode)
+}
+
+// telegramResponse is the envelope of every Bot API response
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+}
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+// telegramDescription returns the error description of a Bot API response
+func telegramDescription(body []byte) string {
+	var resp telegramResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return ""
 	}
+	return resp.Description
+}
 
-	resp, err := client.PostForm(apiURL, url.Values{
-		"chat_id":    {t.ChatID},
-		"text":       {message},
-		"parse_mode": {"Markdown"},
-	})
+// isParseError reports whether Telegram rejected a message because of its markup
+func isParseError(status int, body []byte) bool {
+	return status == http.StatusBadRequest && strings.Contains(telegramDescription(body), "can't parse entities")
+}
+
+// ValidateTelegramAPIURL checks that a Bot API base URL is an absolute HTTP(S) URL
+func ValidateTelegramAPIURL(apiURL string) error {
+	u, err := url.Parse(apiURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid telegram api_url %q: expected http(s)://host[:port][/path]", apiURL)
+	}
+	return nil
+}
+
+// methodURL returns the Bot API endpoint of a method
+func (t *TelegramNotifier) methodURL(method string) string {
+	base := t.APIURL
+	if base == "" {
+		base = DefaultTelegramAPIURL
+	}
+	return fmt.Sprintf("%s/bot%s/%s", strings.TrimRight(base, "/"), t.BotToken, method)
+}
+
+// post calls a message method (sendMessage, editMessageText) for a chat with
+// the given text, parse mode (empty for plain text) and extra parameters, and
+// returns the status code and response body
+func (t *TelegramNotifier) post(chat TelegramDestination, method string, params url.Values, message, parseMode string) (int, []byte, error) {
+	form := url.Values{
+		"chat_id": {chat.ChatID},
+		"text":    {message},
+	}
+	if parseMode != "" {
+		form.Set("parse_mode", parseMode)
+	}
+	for key, values := range params {
+		form[key] = values
+	}
+
+	status, body, err := doRequest("POST", t.methodURL(method), "application/x-www-form-urlencoded",
+		[]byte(form.Encode()), nil)
 	if err != nil {
-		return fmt.Errorf("failed to send telegram message: %w", err)
+		return 0, nil, fmt.Errorf("failed to send telegram message: %w", err)
+	}
+	return status, body, nil
+}
+
+// SendMessage sends a message to every chat via Telegram Bot API. The message
+// must already be formatted for the configured parse mode.
+func (t *TelegramNotifier) SendMessage(message string) error {
+	var errs []error
+	for i, chat := range t.Chats {
+		status, body, err := t.post(chat, "sendMessage", chat.sendParams(), message, t.ParseMode)
+		if err == nil {
+			err = checkStatus("telegram API", status, body)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("chat %d: %w", i+1, err))
+		}
 	}
-	defer resp.Body.Close()
+	return errors.Join(errs...)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("telegram API error (status %d): %s", resp.StatusCode, string(body))
+// deliver builds a message with the configured markup and posts it to a chat
+// with the given method. If Telegram cannot parse the result, the message is
+// rebuilt and resent as plain text so that it is not lost.
+func (t *TelegramNotifier) deliver(chat TelegramDestination, method string, params url.Values, build func(m markup) string) (int, []byte, error) {
+	m, err := t.markup()
+	if err != nil {
+		return 0, nil, err
 	}
 
-	return nil
+	status, body, err := t.post(chat, method, params, build(m), t.ParseMode)
+	if err != nil {
+		return 0, nil, err
+	}
+	if t.ParseMode != "" && isParseError(status, body) {
+		fmt.Printf("⚠️  Telegram rejected the %s message (%s), resending as plain text\n", t.ParseMode, string(body))
+		return t.post(chat, method, params, build(plainMarkup{}), "")
+	}
+	return status, body, nil
 }
 
-// IPStatus holds the status of an IP (current value and whether it changed)
-type IPStatus struct {
-	Current  string
-	Previous string
-	Changed  bool
+// sendFormatted builds a message with the configured markup and sends it to
+// every chat accepting an event known by any of the given names
+func (t *TelegramNotifier) sendFormatted(events []string, build func(m markup) string) error {
+	var errs []error
+	for i, chat := range t.Chats {
+		if !chat.accepts(events...) {
+			continue
+		}
+		status, body, err := t.deliver(chat, "sendMessage", chat.sendParams(), build)
+		if err == nil {
+			err = checkStatus("telegram API", status, body)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("chat %d: %w", i+1, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // SendCombinedIPNotification sends a notification with both IPv4 and IPv6 status
 func (t *TelegramNotifier) SendCombinedIPNotification(hostname string, ipv4, ipv6 IPStatus, timestamp time.Time) error {
-	var title string
-	if (ipv4.Changed && ipv4.Previous == "") || (ipv6.Changed && ipv6.Previous == "") {
-		title = "🌐 *IP Detector Initialized*"
-	} else {
-		title = "🔄 *IP Address Changed*"
-	}
-
-	// Build IPv4 section
-	var ipv4Section string
-	if ipv4.Current != "" {
-		if ipv4.Changed {
-			if ipv4.Previous == "" {
-				ipv4Section = fmt.Sprintf("📍 IPv4: `%s` (new)", ipv4.Current)
-			} else {
-				ipv4Section = fmt.Sprintf("📍 IPv4: `%s` ← `%s`", ipv4.Current, ipv4.Previous)
-			}
-		} else {
-			ipv4Section = fmt.Sprintf("📍 IPv4: `%s`", ipv4.Current)
+	event := ChangeEvent{Hostname: hostname, IPv4: ipv4, IPv6: ipv6, Timestamp: timestamp}
+
+	return t.sendFormatted([]string{TelegramEventChange, event.Kind()}, func(m markup) string {
+		// Summarise labelled link switches above the per-family details
+		var transitions string
+		if lines := formatTransitions(m, event); len(lines) > 0 {
+			transitions = strings.Join(lines, "\n") + "\n\n"
 		}
-	} else {
-		ipv4Section = "📍 IPv4: Not available"
-	}
 
-	// Build IPv6 section
-	var ipv6Section string
-	if ipv6.Current != "" {
-		if ipv6.Changed {
-			if ipv6.Previous == "" {
-				ipv6Section = fmt.Sprintf("📍 IPv6: `%s` (new)", ipv6.Current)
-			} else {
-				ipv6Section = fmt.Sprintf("📍 IPv6: `%s` ← `%s`", ipv6.Current, ipv6.Previous)
-			}
-		} else {
-			ipv6Section = fmt.Sprintf("📍 IPv6: `%s`", ipv6.Current)
+		// Collect per-family check results below the addresses
+		var details string
+		if lines := formatDetails(m, event); len(lines) > 0 {
+			details = strings.Join(lines, "\n") + "\n"
 		}
-	} else {
-		ipv6Section = "📍 IPv6: Not available"
-	}
 
-	message := fmt.Sprintf("%s\n\n"+
-		"🖥️ Host: `%s`\n"+
-		"%s\n"+
-		"%s\n"+
-		"🕐 Time: %s",
-		title, hostname, ipv4Section, ipv6Section, timestamp.Format("2006-01-02 15:04:05 MST"))
+		return formatTitle(m, event) + "\n\n" +
+			transitions +
+			m.text("🖥️ Host: ") + m.code(hostname) + "\n" +
+			formatIPSection(m, "IPv4", ipv4) + "\n" +
+			formatIPSection(m, "IPv6", ipv6) + "\n" +
+			details +
+			m.text("🕐 Time: "+timestamp.Format(timeFormat))
+	})
+}
+
+// SendReachabilityNotification reports a change in port reachability found by
+// a periodic check while the addresses themselves are unchanged
+func (t *TelegramNotifier) SendReachabilityNotification(hostname string, ipv4, ipv6 IPStatus, timestamp time.Time) error {
+	event := ChangeEvent{Hostname: hostname, IPv4: ipv4, IPv6: ipv6, Timestamp: timestamp, PortsChanged: true}
+
+	return t.sendFormatted([]string{TelegramEventChange, KindReachability}, func(m markup) string {
+		var details string
+		if lines := nonEmpty(formatPorts(m, "IPv4", ipv4), formatPorts(m, "IPv6", ipv6)); len(lines) > 0 {
+			details = strings.Join(lines, "\n") + "\n"
+		}
 
-	return t.SendMessage(message)
+		return formatTitle(m, event) + "\n\n" +
+			m.text("🖥️ Host: ") + m.code(hostname) + "\n" +
+			formatIPSection(m, "IPv4", ipv4) + "\n" +
+			formatIPSection(m, "IPv6", ipv6) + "\n" +
+			details +
+			m.text("🕐 Time: "+timestamp.Format(timeFormat))
+	})
 }
 
-// SendTestNotification sends a test notification with hostname
+// SendTestNotification sends a test notification with hostname to every chat
 func (t *TelegramNotifier) SendTestNotification(hostname string) error {
-	message := fmt.Sprintf("✅ *IP Detector Test*\n\n"+
-		"🖥️ Host: `%s`\n"+
-		"Telegram notification is working correctly!", hostname)
-	return t.SendMessage(message)
+	return t.sendFormatted(nil, func(m markup) string {
+		return m.text("✅ ") + m.bold("IP Detector Test") + "\n\n" +
+			m.text("🖥️ Host: ") + m.code(hostname) + "\n" +
+			m.text("Telegram notification is working correctly!")
+	})
+}
+
+// SendChange implements Notifier
+func (t *TelegramNotifier) SendChange(event ChangeEvent) error {
+	if event.Kind() == KindReachability {
+		return t.SendReachabilityNotification(event.Hostname, event.IPv4, event.IPv6, event.Timestamp)
+	}
+	return t.SendCombinedIPNotification(event.Hostname, event.IPv4, event.IPv6, event.Timestamp)
+}
+
+// SendTest implements Notifier
+func (t *TelegramNotifier) SendTest(hostname string) error {
+	return t.SendTestNotification(hostname)
+}
+
+// SendFailure implements Notifier
+func (t *TelegramNotifier) SendFailure(event FailureEvent) error {
+	name := TelegramEventFailure
+	if event.Recovered {
+		name = TelegramEventRecovery
+	}
+	return t.sendFormatted([]string{name}, func(m markup) string {
+		return formatFailureTitle(m, event) + "\n\n" + strings.Join(formatFailureLines(m, event), "\n")
+	})
 }
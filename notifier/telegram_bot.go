@@ -0,0 +1,128 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TelegramUser is a Telegram user or bot
+type TelegramUser struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	Username  string `json:"username,omitempty"`
+}
+
+// TelegramChat is the chat a message was sent in
+type TelegramChat struct {
+	ID        int64  `json:"id"`
+	Type      string `json:"type"`                 // "private", "group", "supergroup" or "channel"
+	Title     string `json:"title,omitempty"`      // groups and channels
+	Username  string `json:"username,omitempty"`   // private chats and public groups/channels
+	FirstName string `json:"first_name,omitempty"` // private chats
+}
+
+// TelegramMessage is an incoming message
+type TelegramMessage struct {
+	MessageID       int64        `json:"message_id"`
+	MessageThreadID int64        `json:"message_thread_id,omitempty"`
+	IsTopicMessage  bool         `json:"is_topic_message,omitempty"` // sent in a forum topic
+	Date            int64        `json:"date"`                       // Unix time
+	Chat            TelegramChat `json:"chat"`
+	Text            string       `json:"text"`
+}
+
+// TelegramUpdate is a single incoming update from getUpdates
+type TelegramUpdate struct {
+	UpdateID    int64            `json:"update_id"`
+	Message     *TelegramMessage `json:"message,omitempty"`
+	ChannelPost *TelegramMessage `json:"channel_post,omitempty"`
+}
+
+// call invokes a Bot API method and decodes its result into result, if non-nil
+func (t *TelegramNotifier) call(method string, params url.Values, timeout time.Duration, result interface{}) error {
+	status, body, err := doRequestTimeout(timeout, "POST", t.methodURL(method),
+		"application/x-www-form-urlencoded", []byte(params.Encode()), nil)
+	if err != nil {
+		return fmt.Errorf("telegram %s failed: %w", method, err)
+	}
+	if err := checkStatus("telegram API", status, body); err != nil {
+		return err
+	}
+
+	var resp struct {
+		telegramResponse
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("failed to parse telegram %s response: %w", method, err)
+	}
+	if !resp.OK {
+		return fmt.Errorf("telegram %s failed: %s", method, resp.Description)
+	}
+	if result != nil {
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("failed to parse telegram %s result: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// GetUpdates long-polls for incoming messages with an update ID of at least
+// offset, waiting up to timeout for one to arrive. Calling it with the ID
+// after the last one received confirms the earlier updates.
+func (t *TelegramNotifier) GetUpdates(offset int64, timeout time.Duration) ([]TelegramUpdate, error) {
+	params := url.Values{
+		"offset":          {strconv.FormatInt(offset, 10)},
+		"timeout":         {strconv.Itoa(int(timeout.Seconds()))},
+		"allowed_updates": {`["message","channel_post"]`},
+	}
+
+	var updates []TelegramUpdate
+	if err := t.call("getUpdates", params, timeout+httpTimeout, &updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// GetMe returns the bot's own user, verifying the bot token
+func (t *TelegramNotifier) GetMe() (*TelegramUser, error) {
+	var user TelegramUser
+	if err := t.call("getMe", url.Values{}, httpTimeout, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// IsConfiguredChat reports whether chatID is one of the chats notifications go to
+func (t *TelegramNotifier) IsConfiguredChat(chatID int64) bool {
+	for _, chat := range t.Chats {
+		if strings.TrimSpace(chat.ChatID) == strconv.FormatInt(chatID, 10) {
+			return true
+		}
+	}
+	return false
+}
+
+// replyDestination returns the chat, and forum topic, a message was sent in
+func replyDestination(msg TelegramMessage) TelegramDestination {
+	chat := TelegramDestination{ChatID: strconv.FormatInt(msg.Chat.ID, 10)}
+	if msg.IsTopicMessage {
+		chat.MessageThreadID = msg.MessageThreadID
+	}
+	return chat
+}
+
+// Reply sends an unformatted answer to a message, in the same forum topic
+func (t *TelegramNotifier) Reply(msg TelegramMessage, text string) error {
+	chat := replyDestination(msg)
+	status, body, err := t.post(chat, "sendMessage", chat.sendParams(), text, "")
+	if err != nil {
+		return err
+	}
+	return checkStatus("telegram API", status, body)
+}
@@ -0,0 +1,84 @@
+package notifier
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime/multipart"
+)
+
+// telegramCaptionLimit is the maximum caption length of a photo, in characters
+const telegramCaptionLimit = 1024
+
+// postPhoto uploads a PNG image with an unformatted caption to a chat
+func (t *TelegramNotifier) postPhoto(chat TelegramDestination, photo []byte, caption string) error {
+	if runes := []rune(caption); len(runes) > telegramCaptionLimit {
+		caption = string(runes[:telegramCaptionLimit-1]) + "…"
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	fields := map[string]string{"chat_id": chat.ChatID, "caption": caption}
+	for key, values := range chat.sendParams() {
+		fields[key] = values[0]
+	}
+	for key, value := range fields {
+		if err := writer.WriteField(key, value); err != nil {
+			return fmt.Errorf("failed to build telegram photo upload: %w", err)
+		}
+	}
+	part, err := writer.CreateFormFile("photo", "chart.png")
+	if err != nil {
+		return fmt.Errorf("failed to build telegram photo upload: %w", err)
+	}
+	if _, err := part.Write(photo); err != nil {
+		return fmt.Errorf("failed to build telegram photo upload: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to build telegram photo upload: %w", err)
+	}
+
+	status, respBody, err := doRequest("POST", t.methodURL("sendPhoto"), writer.FormDataContentType(), body.Bytes(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to send telegram photo: %w", err)
+	}
+	return checkStatus("telegram API", status, respBody)
+}
+
+// AcceptsEvent reports whether any chat receives the given event
+func (t *TelegramNotifier) AcceptsEvent(event string) bool {
+	for _, chat := range t.Chats {
+		if chat.accepts(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// SendPhoto sends a PNG image with an unformatted caption to every chat
+// accepting charts, and returns the number of chats it was delivered to
+func (t *TelegramNotifier) SendPhoto(photo []byte, caption string) (int, error) {
+	if !t.AcceptsEvent(TelegramEventChart) {
+		return 0, fmt.Errorf("no chat accepts the %s event", TelegramEventChart)
+	}
+
+	delivered := 0
+	var errs []error
+	for i, chat := range t.Chats {
+		if !chat.accepts(TelegramEventChart) {
+			continue
+		}
+		if err := t.postPhoto(chat, photo, caption); err != nil {
+			errs = append(errs, fmt.Errorf("chat %d: %w", i+1, err))
+			continue
+		}
+		delivered++
+	}
+	return delivered, errors.Join(errs...)
+}
+
+// ReplyPhoto sends a PNG image with an unformatted caption in answer to a
+// message, in the same forum topic
+func (t *TelegramNotifier) ReplyPhoto(msg TelegramMessage, photo []byte, caption string) error {
+	return t.postPhoto(replyDestination(msg), photo, caption)
+}
@@ -0,0 +1,127 @@
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// formatStatusMessage builds the text of a live status message
+func formatStatusMessage(m markup, report StatusReport) string {
+	health := "✅ working"
+	if !report.Healthy {
+		health = "❌ failing"
+	}
+	lastChange := "never"
+	if !report.LastChange.IsZero() {
+		lastChange = report.LastChange.Format(timeFormat)
+	}
+
+	return m.text("📌 ") + m.bold("IP Status") + "\n\n" +
+		m.text("🖥️ Host: ") + m.code(report.Hostname) + "\n" +
+		formatIPSection(m, "IPv4", report.IPv4) + "\n" +
+		formatIPSection(m, "IPv6", report.IPv6) + "\n" +
+		m.text("🔎 Detection: "+health) + "\n" +
+		m.text("🔄 Last change: "+lastChange) + "\n" +
+		m.text("🕐 Last check: "+report.LastCheck.Format(timeFormat))
+}
+
+// UpdateStatus implements StatusUpdater. Every chat accepting status
+// messages has its own; the existing message is edited, and if there is none
+// yet, or it was deleted, a new one is sent silently and pinned.
+func (t *TelegramNotifier) UpdateStatus(report StatusReport, messages map[string]int64) error {
+	if !t.StatusMessage {
+		return nil
+	}
+
+	var errs []error
+	for i, chat := range t.Chats {
+		if !chat.accepts(TelegramEventStatus) {
+			continue
+		}
+		key, err := chat.key()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("chat %d: %w", i+1, err))
+			continue
+		}
+		// Take over a message remembered under a plaintext key
+		if id, ok := messages[chat.legacyKey()]; ok {
+			delete(messages, chat.legacyKey())
+			if messages[key] == 0 {
+				messages[key] = id
+			}
+		}
+		if err := t.updateStatus(chat, key, report, messages); err != nil {
+			errs = append(errs, fmt.Errorf("chat %d: %w", i+1, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isStatusMessageGone reports whether an edit failed because the status
+// message no longer exists or can no longer be edited, so that a new one has
+// to be sent
+func isStatusMessageGone(status int, body []byte) bool {
+	description := telegramDescription(body)
+	return status == http.StatusBadRequest &&
+		(strings.Contains(description, "message to edit not found") ||
+			strings.Contains(description, "message can't be edited"))
+}
+
+// updateStatus edits or replaces the status message of one chat, remembered
+// under key. Errors other than a missing message are returned, so the next
+// check tries to edit the same message again.
+func (t *TelegramNotifier) updateStatus(chat TelegramDestination, key string, report StatusReport, messages map[string]int64) error {
+	build := func(m markup) string { return formatStatusMessage(m, report) }
+
+	if id := messages[key]; id != 0 {
+		status, body, err := t.deliver(chat, "editMessageText",
+			url.Values{"message_id": {strconv.FormatInt(id, 10)}}, build)
+		if err != nil {
+			return err
+		}
+		if status == http.StatusOK || strings.Contains(telegramDescription(body), "message is not modified") {
+			return nil
+		}
+		if !isStatusMessageGone(status, body) {
+			return checkStatus("telegram API", status, body)
+		}
+		fmt.Printf("⚠️  Telegram status message %d could not be edited (%s), sending a new one\n",
+			id, telegramDescription(body))
+
+		// Best effort: a deleted message is no longer pinned anyway
+		_ = t.call("unpinChatMessage", url.Values{
+			"chat_id":    {chat.ChatID},
+			"message_id": {strconv.FormatInt(id, 10)},
+		}, httpTimeout, nil)
+		delete(messages, key)
+	}
+
+	params := chat.sendParams()
+	params.Set("disable_notification", "true")
+	status, body, err := t.deliver(chat, "sendMessage", params, build)
+	if err != nil {
+		return err
+	}
+	if err := checkStatus("telegram API", status, body); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Result TelegramMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Result.MessageID == 0 {
+		return fmt.Errorf("telegram sendMessage returned no message ID")
+	}
+	messages[key] = resp.Result.MessageID
+
+	return t.call("pinChatMessage", url.Values{
+		"chat_id":              {chat.ChatID},
+		"message_id":           {strconv.FormatInt(resp.Result.MessageID, 10)},
+		"disable_notification": {"true"},
+	}, httpTimeout, nil)
+}
@@ -0,0 +1,150 @@
+package notifier
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"ip_detector/storage"
+)
+
+func newStatusNotifier(t *testing.T, stub *telegramStub) *TelegramNotifier {
+	t.Helper()
+	return newTestTelegram(t, telegramOptions{
+		BotToken:      "123:abc",
+		APIURL:        stub.URL,
+		Chats:         []TelegramDestination{{ChatID: "-100200", MessageThreadID: 7}},
+		StatusMessage: true,
+	})
+}
+
+// statusKey returns the key a chat's status message is remembered under
+func statusKey(t *testing.T, chat TelegramDestination) string {
+	t.Helper()
+	key, err := chat.key()
+	if err != nil {
+		t.Fatalf("key: %v", err)
+	}
+	return key
+}
+
+func testStatusReport() StatusReport {
+	return StatusReport{
+		Hostname:  "vm-1",
+		IPv4:      IPStatus{Current: "5.6.7.8"},
+		LastCheck: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Healthy:   true,
+	}
+}
+
+func TestStatusMessageKeysHideChatID(t *testing.T) {
+	stub := newTelegramStub(t)
+	tg := newStatusNotifier(t, stub)
+
+	messages := map[string]int64{}
+	if err := tg.UpdateStatus(testStatusReport(), messages); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+	if got := strings.Join(stub.methods(), ","); got != "sendMessage,pinChatMessage" {
+		t.Errorf("methods = %s, want sendMessage,pinChatMessage", got)
+	}
+
+	// The key is an HMAC under the storage key; a plain hash of the short
+	// numeric chat ID could be reversed by trying every ID
+	fingerprint, err := storage.Fingerprint("-100200/7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "hmac:" + fingerprint[:32]
+	if len(messages) != 1 || messages[want] == 0 {
+		t.Fatalf("messages = %v, want one entry under %s", messages, want)
+	}
+	plain := sha256.Sum256([]byte("-100200/7"))
+	if strings.Contains(want, hex.EncodeToString(plain[:8])) {
+		t.Errorf("key %q is an unkeyed hash of the chat ID", want)
+	}
+}
+
+func TestStatusMessageLegacyKey(t *testing.T) {
+	stub := newTelegramStub(t)
+	tg := newStatusNotifier(t, stub)
+
+	messages := map[string]int64{"-100200/7": 42}
+	if err := tg.UpdateStatus(testStatusReport(), messages); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+	if got := strings.Join(stub.methods(), ","); got != "editMessageText" {
+		t.Errorf("methods = %s, want editMessageText", got)
+	}
+	if got := stub.lastCall(t, "editMessageText").Params.Get("message_id"); got != "42" {
+		t.Errorf("edited message %s, want 42", got)
+	}
+	if _, ok := messages["-100200/7"]; ok || len(messages) != 1 {
+		t.Errorf("messages = %v, want the legacy key replaced", messages)
+	}
+}
+
+func TestStatusMessageReplacedWhenNotFound(t *testing.T) {
+	stub := newTelegramStub(t)
+	stub.respond("editMessageText", telegramError(http.StatusBadRequest, "Bad Request: message to edit not found"))
+	tg := newStatusNotifier(t, stub)
+
+	key := statusKey(t, tg.Chats[0])
+	messages := map[string]int64{key: 42}
+	if err := tg.UpdateStatus(testStatusReport(), messages); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+	if got := strings.Join(stub.methods(), ","); got != "editMessageText,unpinChatMessage,sendMessage,pinChatMessage" {
+		t.Errorf("methods = %s", got)
+	}
+	if got := stub.lastCall(t, "unpinChatMessage").Params.Get("message_id"); got != "42" {
+		t.Errorf("unpinned message %s, want 42", got)
+	}
+	if messages[key] == 42 || messages[key] == 0 {
+		t.Errorf("message ID = %d, want the new message", messages[key])
+	}
+}
+
+func TestStatusMessageKeptOnTemporaryError(t *testing.T) {
+	for _, tc := range []struct {
+		status      int
+		description string
+	}{
+		{http.StatusTooManyRequests, "Too Many Requests: retry after 5"},
+		{http.StatusBadGateway, "Bad Gateway"},
+	} {
+		stub := newTelegramStub(t)
+		stub.respond("editMessageText", telegramError(tc.status, tc.description))
+		tg := newStatusNotifier(t, stub)
+
+		key := statusKey(t, tg.Chats[0])
+		messages := map[string]int64{key: 42}
+		if err := tg.UpdateStatus(testStatusReport(), messages); err == nil {
+			t.Errorf("status %d: UpdateStatus succeeded, want error", tc.status)
+		}
+		if got := strings.Join(stub.methods(), ","); got != "editMessageText" {
+			t.Errorf("status %d: methods = %s, want only editMessageText", tc.status, got)
+		}
+		if messages[key] != 42 {
+			t.Errorf("status %d: message ID = %d, want 42 kept", tc.status, messages[key])
+		}
+	}
+}
+
+func TestStatusMessageNotModified(t *testing.T) {
+	stub := newTelegramStub(t)
+	stub.respond("editMessageText", telegramError(http.StatusBadRequest,
+		"Bad Request: message is not modified: specified new message content and reply markup are exactly the same"))
+	tg := newStatusNotifier(t, stub)
+
+	messages := map[string]int64{statusKey(t, tg.Chats[0]): 42}
+	if err := tg.UpdateStatus(testStatusReport(), messages); err != nil {
+		t.Errorf("UpdateStatus: %v", err)
+	}
+	if got := strings.Join(stub.methods(), ","); got != "editMessageText" {
+		t.Errorf("methods = %s, want only editMessageText", got)
+	}
+}
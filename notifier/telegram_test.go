@@ -0,0 +1,210 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// telegramCall is a Bot API request received by the stub server
+type telegramCall struct {
+	Path   string
+	Method string // Bot API method, e.g. "sendMessage"
+	Params url.Values
+}
+
+// telegramStub is a fake Bot API server. Responses are looked up by method
+// and default to a successful result.
+type telegramStub struct {
+	*httptest.Server
+	mu        sync.Mutex
+	calls     []telegramCall
+	responses map[string]func(params url.Values) (int, string)
+	nextID    int64
+}
+
+func newTelegramStub(t *testing.T) *telegramStub {
+	t.Helper()
+	stub := &telegramStub{responses: make(map[string]func(url.Values) (int, string)), nextID: 100}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("invalid form: %v", err)
+		}
+		method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+		stub.mu.Lock()
+		stub.calls = append(stub.calls, telegramCall{Path: r.URL.Path, Method: method, Params: r.PostForm})
+		respond := stub.responses[method]
+		stub.nextID++
+		id := stub.nextID
+		stub.mu.Unlock()
+
+		status, body := http.StatusOK, `{"ok":true,"result":true}`
+		if respond != nil {
+			status, body = respond(r.PostForm)
+		} else if method == "sendMessage" {
+			body = fmt.Sprintf(`{"ok":true,"result":{"message_id":%d}}`, id)
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+// respond sets the response of a Bot API method
+func (s *telegramStub) respond(method string, fn func(params url.Values) (int, string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[method] = fn
+}
+
+// methods returns the Bot API methods called so far, in order
+func (s *telegramStub) methods() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var methods []string
+	for _, call := range s.calls {
+		methods = append(methods, call.Method)
+	}
+	return methods
+}
+
+// lastCall returns the last call of a Bot API method
+func (s *telegramStub) lastCall(t *testing.T, method string) telegramCall {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.calls) - 1; i >= 0; i-- {
+		if s.calls[i].Method == method {
+			return s.calls[i]
+		}
+	}
+	t.Fatalf("%s was not called", method)
+	return telegramCall{}
+}
+
+// telegramError returns a Bot API error response
+func telegramError(status int, description string) func(url.Values) (int, string) {
+	return func(url.Values) (int, string) {
+		return status, fmt.Sprintf(`{"ok":false,"error_code":%d,"description":%q}`, status, description)
+	}
+}
+
+// newTestTelegram creates a telegram notifier through the registry
+func newTestTelegram(t *testing.T, opts telegramOptions) *TelegramNotifier {
+	t.Helper()
+	options, err := json.Marshal(opts)
+	if err != nil {
+		t.Fatalf("failed to serialize options: %v", err)
+	}
+	n, err := New("telegram", options)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return n.(*TelegramNotifier)
+}
+
+func TestTelegramCustomAPIURL(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		suffix string
+		path   string
+	}{
+		{"root", "", "/bot123:abc/sendMessage"},
+		{"trailing slash", "/", "/bot123:abc/sendMessage"},
+		{"path prefix", "/telegram", "/telegram/bot123:abc/sendMessage"},
+		{"path prefix with trailing slash", "/telegram/", "/telegram/bot123:abc/sendMessage"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := newTelegramStub(t)
+			tg := newTestTelegram(t, telegramOptions{BotToken: "123:abc", APIURL: stub.URL + tc.suffix, ChatID: "42"})
+
+			if err := tg.SendTest("vm-1"); err != nil {
+				t.Fatalf("SendTest: %v", err)
+			}
+			call := stub.lastCall(t, "sendMessage")
+			if call.Path != tc.path {
+				t.Errorf("path = %s, want %s", call.Path, tc.path)
+			}
+			if got := call.Params.Get("chat_id"); got != "42" {
+				t.Errorf("chat_id = %s, want 42", got)
+			}
+			if got := call.Params.Get("parse_mode"); got != "HTML" {
+				t.Errorf("parse_mode = %s, want HTML", got)
+			}
+		})
+	}
+}
+
+func TestTelegramParseErrorFallback(t *testing.T) {
+	stub := newTelegramStub(t)
+	stub.respond("sendMessage", func(params url.Values) (int, string) {
+		if params.Get("parse_mode") != "" {
+			return telegramError(http.StatusBadRequest, "Bad Request: can't parse entities: unsupported start tag")(params)
+		}
+		return http.StatusOK, `{"ok":true,"result":{"message_id":1}}`
+	})
+	tg := newTestTelegram(t, telegramOptions{BotToken: "123:abc", APIURL: stub.URL, ChatID: "42"})
+
+	event := ChangeEvent{
+		Hostname: "<vm & co>",
+		IPv4:     IPStatus{Current: "5.6.7.8", Previous: "1.2.3.4", Changed: true, Label: "a<b"},
+	}
+	if err := tg.SendChange(event); err != nil {
+		t.Fatalf("SendChange: %v", err)
+	}
+
+	if got := strings.Join(stub.methods(), ","); got != "sendMessage,sendMessage" {
+		t.Fatalf("methods = %s, want two sendMessage calls", got)
+	}
+	plain := stub.lastCall(t, "sendMessage").Params
+	if plain.Get("parse_mode") != "" {
+		t.Errorf("fallback parse_mode = %q, want none", plain.Get("parse_mode"))
+	}
+	text := plain.Get("text")
+	if !strings.Contains(text, "<vm & co>") || !strings.Contains(text, "5.6.7.8 (a<b)") {
+		t.Errorf("fallback text is not plain: %q", text)
+	}
+	if strings.Contains(text, "<b>") || strings.Contains(text, "&amp;") {
+		t.Errorf("fallback text contains HTML: %q", text)
+	}
+}
+
+func TestTelegramErrorNotRetriedAsPlain(t *testing.T) {
+	stub := newTelegramStub(t)
+	stub.respond("sendMessage", telegramError(http.StatusForbidden, "Forbidden: bot was blocked by the user"))
+	tg := newTestTelegram(t, telegramOptions{BotToken: "123:abc", APIURL: stub.URL, ChatID: "42"})
+
+	if err := tg.SendTest("vm-1"); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("SendTest error = %v, want status 403", err)
+	}
+	if got := strings.Join(stub.methods(), ","); got != "sendMessage" {
+		t.Errorf("methods = %s, want a single sendMessage", got)
+	}
+}
+
+func TestTelegramSendPhotoWithoutChartChats(t *testing.T) {
+	stub := newTelegramStub(t)
+	tg := newTestTelegram(t, telegramOptions{
+		BotToken: "123:abc",
+		APIURL:   stub.URL,
+		Chats:    []TelegramDestination{{ChatID: "42", Events: []string{"change"}}},
+	})
+
+	if tg.AcceptsEvent(TelegramEventChart) {
+		t.Error("AcceptsEvent(chart) = true for a chat receiving only changes")
+	}
+	delivered, err := tg.SendPhoto([]byte("png"), "caption")
+	if delivered != 0 || err == nil {
+		t.Errorf("SendPhoto = %d, %v; want an error for no eligible chat", delivered, err)
+	}
+	if methods := stub.methods(); len(methods) != 0 {
+		t.Errorf("methods = %v, want no request", methods)
+	}
+}
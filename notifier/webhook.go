@@ -0,0 +1,317 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// WebhookOptions configure a "webhook" notifier
+type WebhookOptions struct {
+	URL         string            `json:"url"`
+	Method      string            `json:"method,omitempty"`       // default POST
+	Format      string            `json:"format,omitempty"`       // "json" (default) or "cloudevents"
+	Headers     map[string]string `json:"headers,omitempty"`      // extra request headers
+	Template    string            `json:"template,omitempty"`     // Go text/template for the body
+	ContentType string            `json:"content_type,omitempty"` // default application/json
+	Secret      string            `json:"secret,omitempty"`       // HMAC-SHA256 key for X-Signature
+}
+
+// WebhookNotifier POSTs a JSON description of each event to a URL
+type WebhookNotifier struct {
+	opts     WebhookOptions
+	template *template.Template
+}
+
+// WebhookPayload is the JSON body sent for each event. For change events one
+// payload is sent per changed address family, and for reachability reports
+// one per detected family.
+type WebhookPayload struct {
+	Event     string   `json:"event"` // init, change, failover, failback, reachability, failure, recovery or test
+	Hostname  string   `json:"hostname"`
+	Family    string   `json:"family,omitempty"` // "ipv4" or "ipv6"
+	OldIP     string   `json:"old_ip,omitempty"`
+	NewIP     string   `json:"new_ip,omitempty"`
+	OldLabel  string   `json:"old_label,omitempty"`
+	NewLabel  string   `json:"new_label,omitempty"`
+	Service   string   `json:"service,omitempty"`
+	PTR       string   `json:"ptr,omitempty"`
+	DNSBL     []string `json:"dnsbl_listed,omitempty"`
+	DNSBLErrs []string `json:"dnsbl_errors,omitempty"` // blocklists that could not be queried
+	Error     string   `json:"error,omitempty"`
+	Timestamp string   `json:"timestamp"`
+}
+
+// cloudEvent is a CloudEvents 1.0 envelope in structured content mode
+type cloudEvent struct {
+	SpecVersion     string         `json:"specversion"`
+	Type            string         `json:"type"`
+	Source          string         `json:"source"`
+	ID              string         `json:"id"`
+	Time            string         `json:"time"`
+	DataContentType string         `json:"datacontenttype"`
+	Data            WebhookPayload `json:"data"`
+}
+
+func init() {
+	Register("webhook", func(options json.RawMessage) (Notifier, error) {
+		var opts WebhookOptions
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return NewWebhookNotifier(opts)
+	})
+	RegisterScheme("generic", "webhook", parseGenericURL)
+	RegisterScheme("generic+http", "webhook", parseGenericURL)
+	RegisterScheme("generic+https", "webhook", parseGenericURL)
+}
+
+// parseGenericURL parses "generic+https://host/path?method=PUT&@Header=value".
+// The method, format, secret and contenttype parameters configure the
+// webhook, "@Name" parameters become headers and the remaining query is kept
+// in the target URL; prefix a parameter with "_" to pass a reserved name
+// through to the target. "generic://" uses https.
+func parseGenericURL(u *url.URL) (interface{}, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("generic url needs a host: generic+https://host/path")
+	}
+	opts := WebhookOptions{}
+	target := url.Values{}
+	for key, values := range u.Query() {
+		value := values[len(values)-1]
+		switch {
+		case key == "method":
+			opts.Method = strings.ToUpper(value)
+		case key == "format":
+			opts.Format = value
+		case key == "secret":
+			opts.Secret = value
+		case key == "contenttype":
+			opts.ContentType = value
+		case strings.HasPrefix(key, "@"):
+			if opts.Headers == nil {
+				opts.Headers = make(map[string]string)
+			}
+			opts.Headers[key[1:]] = value
+		case strings.HasPrefix(key, "_"):
+			target[key[1:]] = values
+		default:
+			target[key] = values
+		}
+	}
+
+	scheme := "https"
+	if strings.EqualFold(u.Scheme, "generic+http") {
+		scheme = "http"
+	}
+	dest := url.URL{Scheme: scheme, User: u.User, Host: u.Host, Path: u.Path, RawQuery: target.Encode()}
+	opts.URL = dest.String()
+	return opts, nil
+}
+
+// NewWebhookNotifier creates a webhook notifier from its options
+func NewWebhookNotifier(opts WebhookOptions) (*WebhookNotifier, error) {
+	if opts.URL == "" {
+		return nil, fmt.Errorf("webhook notifier needs a url")
+	}
+
+	switch opts.Format {
+	case "":
+		opts.Format = "json"
+	case "json", "cloudevents":
+	default:
+		return nil, fmt.Errorf("unsupported webhook format %q", opts.Format)
+	}
+
+	w := &WebhookNotifier{opts: opts}
+	if opts.Template != "" {
+		tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+			"json": func(v interface{}) (string, error) {
+				data, err := json.Marshal(v)
+				return string(data), err
+			},
+		}).Parse(opts.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook template: %w", err)
+		}
+		w.template = tmpl
+	}
+	return w, nil
+}
+
+// familyPayload builds the payload for one changed address family
+func familyPayload(event ChangeEvent, family string, status IPStatus) WebhookPayload {
+	return WebhookPayload{
+		Event:     event.Kind(),
+		Hostname:  event.Hostname,
+		Family:    family,
+		OldIP:     status.Previous,
+		NewIP:     status.Current,
+		OldLabel:  status.PreviousLabel,
+		NewLabel:  status.Label,
+		Service:   status.Service,
+		PTR:       status.PTR,
+		DNSBL:     status.DNSBLListed,
+		DNSBLErrs: status.DNSBLErrors,
+		Timestamp: event.Timestamp.Format(time.RFC3339),
+	}
+}
+
+// SendChange implements Notifier
+func (w *WebhookNotifier) SendChange(event ChangeEvent) error {
+	var payloads []WebhookPayload
+	if event.IPv4.Changed {
+		payloads = append(payloads, familyPayload(event, "ipv4", event.IPv4))
+	}
+	if event.IPv6.Changed {
+		payloads = append(payloads, familyPayload(event, "ipv6", event.IPv6))
+	}
+	if len(payloads) == 0 {
+		// Reachability reports cover the current address of each family
+		for _, f := range []struct {
+			family string
+			status IPStatus
+		}{{"ipv4", event.IPv4}, {"ipv6", event.IPv6}} {
+			if f.status.Current == "" {
+				continue
+			}
+			payloads = append(payloads, WebhookPayload{
+				Event:     event.Kind(),
+				Hostname:  event.Hostname,
+				Family:    f.family,
+				NewIP:     f.status.Current,
+				NewLabel:  f.status.Label,
+				Timestamp: event.Timestamp.Format(time.RFC3339),
+			})
+		}
+	}
+	if len(payloads) == 0 {
+		payloads = append(payloads, WebhookPayload{
+			Event:     event.Kind(),
+			Hostname:  event.Hostname,
+			Timestamp: event.Timestamp.Format(time.RFC3339),
+		})
+	}
+
+	// Send every payload even if one fails, so one family's failure does not
+	// hide the other's change
+	var errs []error
+	for _, payload := range payloads {
+		if err := w.send(payload); err != nil {
+			if payload.Family != "" {
+				err = fmt.Errorf("%s: %w", payload.Family, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SendTest implements Notifier
+func (w *WebhookNotifier) SendTest(hostname string) error {
+	return w.send(WebhookPayload{
+		Event:     "test",
+		Hostname:  hostname,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// SendFailure implements Notifier
+func (w *WebhookNotifier) SendFailure(event FailureEvent) error {
+	kind := "failure"
+	if event.Recovered {
+		kind = "recovery"
+	}
+	return w.send(WebhookPayload{
+		Event:     kind,
+		Hostname:  event.Hostname,
+		Error:     event.Error,
+		Timestamp: event.Timestamp.Format(time.RFC3339),
+	})
+}
+
+// render builds the request body and content type for a payload
+func (w *WebhookNotifier) render(payload WebhookPayload) ([]byte, string, error) {
+	var data interface{} = payload
+	contentType := "application/json"
+	if w.opts.Format == "cloudevents" {
+		id, err := randomID()
+		if err != nil {
+			return nil, "", err
+		}
+		data = cloudEvent{
+			SpecVersion:     "1.0",
+			Type:            "io.ip_detector." + payload.Event,
+			Source:          "ip_detector/" + payload.Hostname,
+			ID:              id,
+			Time:            payload.Timestamp,
+			DataContentType: "application/json",
+			Data:            payload,
+		}
+		contentType = "application/cloudevents+json"
+	}
+	if w.opts.ContentType != "" {
+		contentType = w.opts.ContentType
+	}
+
+	if w.template != nil {
+		var buf bytes.Buffer
+		if err := w.template.Execute(&buf, data); err != nil {
+			return nil, "", fmt.Errorf("failed to render webhook template: %w", err)
+		}
+		return buf.Bytes(), contentType, nil
+	}
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to serialize payload: %w", err)
+	}
+	return body, contentType, nil
+}
+
+// send renders, signs and delivers a single payload
+func (w *WebhookNotifier) send(payload WebhookPayload) error {
+	body, contentType, err := w.render(payload)
+	if err != nil {
+		return err
+	}
+
+	headers := make(map[string]string, len(w.opts.Headers)+1)
+	for key, value := range w.opts.Headers {
+		headers[key] = value
+	}
+	if w.opts.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.opts.Secret))
+		mac.Write(body)
+		headers["X-Signature"] = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	method := w.opts.Method
+	if method == "" {
+		method = "POST"
+	}
+
+	status, respBody, err := doRequest(method, w.opts.URL, contentType, body, headers)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	return checkStatus("webhook", status, respBody)
+}
+
+// randomID returns a random 128-bit hex identifier
+func randomID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"ip_detector/config"
+	"ip_detector/hooks"
+	"ip_detector/notifier"
+)
+
+// buildChannels creates a notifier for every enabled channel in the
+// configuration. Channels that cannot be created are reported and skipped so
+// that one broken channel does not silence the others.
+func buildChannels(cfg *config.Config) []notifier.Channel {
+	var channels []notifier.Channel
+	for _, nc := range cfg.NotifierConfigs() {
+		if !nc.Enabled {
+			continue
+		}
+
+		n, err := newNotifier(nc)
+		if err != nil {
+			fmt.Printf("⚠️  Notifier %s: %v\n", nc.Name, err)
+			continue
+		}
+		channels = append(channels, notifier.Channel{Name: nc.Name, Notifier: n})
+	}
+	return channels
+}
+
+// newNotifier creates the notifier of a channel from its URL or its type and
+// options
+func newNotifier(nc config.NotifierConfig) (notifier.Notifier, error) {
+	if nc.URL != "" {
+		rawURL, err := config.RevealSecret(nc.URL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt url: %w", err)
+		}
+		return notifier.NewFromURL(rawURL)
+	}
+
+	options, err := nc.DecryptedOptions()
+	if err != nil {
+		return nil, err
+	}
+	return notifier.New(nc.Type, options)
+}
+
+// notifyAll delivers a notification through every enabled channel, printing
+// the result of each, and returns an error if any channel failed
+func notifyAll(cfg *config.Config, send func(notifier.Notifier) error) error {
+	_, err := broadcast(cfg, send)
+	return err
+}
+
+// broadcast is notifyAll that also returns the number of channels the
+// notification was delivered through
+func broadcast(cfg *config.Config, send func(notifier.Notifier) error) (int, error) {
+	channels := buildChannels(cfg)
+	if len(channels) == 0 {
+		return 0, fmt.Errorf("no notification channels configured")
+	}
+
+	failed := 0
+	for _, result := range notifier.Broadcast(channels, send) {
+		if result.Err != nil {
+			fmt.Printf("❌ %s: %v\n", result.Channel, result.Err)
+			failed++
+		} else {
+			fmt.Printf("✅ %s: delivered\n", result.Channel)
+		}
+	}
+
+	if failed > 0 {
+		return len(channels) - failed, fmt.Errorf("failed to send notification via %d of %d channel(s)", failed, len(channels))
+	}
+	return len(channels), nil
+}
+
+// notificationsMuted reports whether event notifications are muted by the
+// /mute bot command, printing a note if so
+func notificationsMuted(cfg *config.Config, now time.Time) bool {
+	until, err := time.Parse(time.RFC3339, cfg.MutedUntil)
+	if err != nil || !now.Before(until) {
+		return false
+	}
+	fmt.Printf("🔕 Notifications muted until %s, not sending.\n", until.Local().Format(time.RFC1123))
+	return true
+}
+
+// reportDetectionHealth sends a failure notification when detection fails,
+// and a recovery notification once detection works again. The state is kept
+// in the configuration so hooks run only once per change, while the
+// notification is retried on later checks until a channel delivered it.
+func reportDetectionHealth(cfg *config.Config, hostname string, detectErr error, now time.Time) error {
+	failing := detectErr != nil
+	if failing != cfg.DetectionFailing {
+		cfg.DetectionFailing = failing
+		cfg.DetectionAlertPending = true
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+
+		hookEvent := hooks.Event{
+			Type:      hooks.EventRecovery,
+			Hostname:  hostname,
+			Timestamp: now.Format(time.RFC3339),
+		}
+		if failing {
+			hookEvent.Type = hooks.EventFailure
+			hookEvent.Error = detectErr.Error()
+		}
+		runHooks(cfg, []hooks.Event{hookEvent})
+	}
+	if !cfg.DetectionAlertPending {
+		return nil
+	}
+
+	event := notifier.FailureEvent{
+		Hostname:  hostname,
+		Recovered: !failing,
+		Timestamp: now,
+	}
+	if failing {
+		event.Error = detectErr.Error()
+	}
+
+	var err error
+	if !notificationsMuted(cfg, now) {
+		var delivered int
+		delivered, err = broadcast(cfg, func(n notifier.Notifier) error {
+			return n.SendFailure(event)
+		})
+		if delivered == 0 {
+			return err
+		}
+	}
+
+	cfg.DetectionAlertPending = false
+	if saveErr := cfg.Save(); saveErr != nil {
+		return fmt.Errorf("failed to save configuration: %w", saveErr)
+	}
+	return err
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"time"
+
+	"ip_detector/config"
+	"ip_detector/detector"
+	"ip_detector/mqtt"
+	"ip_detector/notifier"
+)
+
+// publishState publishes the current addresses and detection health to the
+// configured MQTT broker. Failures are reported but never abort a check.
+func publishState(cfg *config.Config, hostname, ipv4, ipv6 string, now time.Time) {
+	if cfg.MQTT == nil || !cfg.MQTT.Enabled {
+		return
+	}
+	if err := publishMQTT(cfg, hostname, ipv4, ipv6, now); err != nil {
+		fmt.Printf("⚠️  MQTT publish failed: %v\n", err)
+	}
+}
+
+// publishMQTT connects to the broker and publishes the state
+func publishMQTT(cfg *config.Config, hostname, ipv4, ipv6 string, now time.Time) error {
+	mc := cfg.MQTT
+
+	password, err := config.RevealSecret(mc.Password)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt MQTT password: %w", err)
+	}
+
+	opts := mqtt.Options{
+		Broker:   mc.Broker,
+		ClientID: mc.ClientID,
+		Username: mc.Username,
+		Password: password,
+	}
+	switch mc.ProtocolVersion {
+	case "", "3.1.1", "4":
+		opts.Version = mqtt.Version311
+	case "5", "5.0":
+		opts.Version = mqtt.Version5
+	default:
+		return fmt.Errorf("unsupported MQTT protocol version %q", mc.ProtocolVersion)
+	}
+	if mc.TLS != nil {
+		opts.TLS, err = detector.BuildTLSConfig(detector.TLSOptions{
+			CAFile:     mc.TLS.CAFile,
+			CertFile:   mc.TLS.CertFile,
+			KeyFile:    mc.TLS.KeyFile,
+			MinVersion: mc.TLS.MinVersion,
+			Pins:       mc.TLS.Pins,
+			ServerName: mc.TLS.ServerName,
+		})
+		if err != nil {
+			return fmt.Errorf("invalid MQTT TLS settings: %w", err)
+		}
+	}
+	if mc.QoS < 0 || mc.QoS > 1 {
+		return fmt.Errorf("unsupported MQTT QoS %d", mc.QoS)
+	}
+
+	state := mqtt.State{
+		Hostname:  hostname,
+		IPv4:      ipv4,
+		IPv6:      ipv6,
+		LastCheck: now,
+		Healthy:   !cfg.DetectionFailing,
+	}
+	if changed, err := time.Parse(time.RFC3339, cfg.LastChanged); err == nil {
+		state.LastChange = changed
+	}
+
+	return mqtt.PublishState(opts, mqtt.PublishOptions{
+		TopicPrefix:     mc.TopicPrefix,
+		Discovery:       mc.Discovery,
+		DiscoveryPrefix: mc.DiscoveryPrefix,
+		QoS:             byte(mc.QoS),
+	}, state)
+}
+
+// updateStatusMessages refreshes the live status message of every channel
+// that keeps one, remembering new message IDs in the configuration
+func updateStatusMessages(cfg *config.Config, hostname, ipv4, ipv6 string, now time.Time) {
+	report := notifier.StatusReport{
+		Hostname:  hostname,
+		IPv4:      buildIPStatus(cfg, ipv4, ipv4, ""),
+		IPv6:      buildIPStatus(cfg, ipv6, ipv6, ""),
+		LastCheck: now,
+		Healthy:   !cfg.DetectionFailing,
+	}
+	if changed, err := time.Parse(time.RFC3339, cfg.LastChanged); err == nil {
+		report.LastChange = changed
+	}
+
+	updated := false
+	for _, channel := range buildChannels(cfg) {
+		su, ok := channel.Notifier.(notifier.StatusUpdater)
+		if !ok {
+			continue
+		}
+
+		messages := make(map[string]int64)
+		for dest, id := range cfg.StatusMessages[channel.Name] {
+			messages[dest] = id
+		}
+		if err := su.UpdateStatus(report, messages); err != nil {
+			fmt.Printf("⚠️  %s: status message update failed: %v\n", channel.Name, err)
+		}
+
+		if maps.Equal(messages, cfg.StatusMessages[channel.Name]) {
+			continue
+		}
+		if cfg.StatusMessages == nil {
+			cfg.StatusMessages = make(map[string]map[string]int64)
+		}
+		if len(messages) == 0 {
+			delete(cfg.StatusMessages, channel.Name)
+		} else {
+			cfg.StatusMessages[channel.Name] = messages
+		}
+		updated = true
+	}
+
+	if updated {
+		if err := cfg.Save(); err != nil {
+			fmt.Printf("⚠️  Failed to save status message IDs: %v\n", err)
+		}
+	}
+}
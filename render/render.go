@@ -0,0 +1,181 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"ip_detector/hooks"
+)
+
+// reloadTimeout bounds each reload command
+const reloadTimeout = 60 * time.Second
+
+// Target is a template rendered to a destination file
+type Target struct {
+	Source        string      // Go text/template file
+	Destination   string      // rendered file, replaced atomically
+	Mode          os.FileMode // permissions of the destination
+	ReloadCommand string      // run with /bin/sh -c after the destination changed
+}
+
+// Data is passed to every template
+type Data struct {
+	Hostname  string
+	IPv4      string
+	IPv6      string
+	IPv4Label string
+	IPv6Label string
+	Timestamp time.Time // time of the last address change, so it only changes with the addresses
+}
+
+// Result holds the outcome of rendering one target
+type Result struct {
+	Target
+	Changed  bool // the destination was written
+	Reloaded bool // the reload command ran successfully
+	Err      error
+}
+
+// funcs are the helpers available to templates in addition to the builtins
+var funcs = template.FuncMap{
+	"default": func(fallback, value string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	},
+	"join":  strings.Join,
+	"split": strings.Split,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// Render renders every target with the given data. A destination is only
+// written, and its reload command only run, when the output differs from
+// the current file content. If the reload command fails, the previous content
+// is restored so that the next render writes and reloads again.
+func Render(targets []Target, data Data) []Result {
+	results := make([]Result, 0, len(targets))
+	for _, target := range targets {
+		results = append(results, renderTarget(target, data))
+	}
+	return results
+}
+
+// renderTarget renders a single target
+func renderTarget(target Target, data Data) Result {
+	result := Result{Target: target}
+
+	source, err := os.ReadFile(target.Source)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to read template: %w", err)
+		return result
+	}
+
+	tmpl, err := template.New(filepath.Base(target.Source)).Funcs(funcs).Option("missingkey=error").Parse(string(source))
+	if err != nil {
+		result.Err = fmt.Errorf("failed to parse template: %w", err)
+		return result
+	}
+
+	var output bytes.Buffer
+	if err := tmpl.Execute(&output, data); err != nil {
+		result.Err = fmt.Errorf("failed to render template: %w", err)
+		return result
+	}
+
+	current, err := os.ReadFile(target.Destination)
+	existed := err == nil
+	if existed && bytes.Equal(current, output.Bytes()) {
+		return result
+	}
+	if err != nil && !os.IsNotExist(err) {
+		result.Err = fmt.Errorf("failed to read destination: %w", err)
+		return result
+	}
+
+	if err := writeAtomic(target.Destination, output.Bytes(), target.Mode); err != nil {
+		result.Err = err
+		return result
+	}
+	result.Changed = true
+
+	if target.ReloadCommand != "" {
+		if err := reload(target.ReloadCommand); err != nil {
+			result.Err = err
+			if restoreErr := restore(target, current, existed); restoreErr != nil {
+				result.Err = fmt.Errorf("%w; %v", err, restoreErr)
+			}
+			return result
+		}
+		result.Reloaded = true
+	}
+	return result
+}
+
+// restore puts back the destination content from before a failed reload, or
+// removes a destination that did not exist
+func restore(target Target, previous []byte, existed bool) error {
+	if !existed {
+		if err := os.Remove(target.Destination); err != nil {
+			return fmt.Errorf("failed to remove destination after failed reload: %w", err)
+		}
+		return nil
+	}
+	if err := writeAtomic(target.Destination, previous, target.Mode); err != nil {
+		return fmt.Errorf("failed to restore destination after failed reload: %w", err)
+	}
+	return nil
+}
+
+// writeAtomic writes data to a temporary file in the destination directory
+// and renames it into place, so readers never see a partial file
+func writeAtomic(path string, data []byte, mode os.FileMode) error {
+	if mode == 0 {
+		mode = 0644
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	if err := os.Chmod(tmpName, mode); err != nil {
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("failed to replace destination: %w", err)
+	}
+	return nil
+}
+
+// reload runs a reload command
+func reload(command string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), reloadTimeout)
+	defer cancel()
+
+	output, err := hooks.ShellCommand(ctx, command).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("reload command failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"ip_detector/config"
+	"ip_detector/hooks"
+	"ip_detector/notifier"
+)
+
+// runHooks runs the configured hooks for the given events, prints the outcome
+// of each and returns the history records per address family
+func runHooks(cfg *config.Config, events []hooks.Event) map[string][]config.HookRecord {
+	if len(cfg.Hooks) == 0 || len(events) == 0 {
+		return nil
+	}
+
+	configured := make([]hooks.Hook, 0, len(cfg.Hooks))
+	for _, hc := range cfg.Hooks {
+		configured = append(configured, hooks.Hook{
+			Name:    hc.Name,
+			Command: hc.Command,
+			Events:  hc.Events,
+			Timeout: time.Duration(hc.TimeoutSeconds) * time.Second,
+		})
+	}
+
+	records := make(map[string][]config.HookRecord)
+	for _, result := range hooks.Run(configured, events, cfg.HookConcurrency) {
+		record := config.HookRecord{
+			Name:       result.Hook,
+			ExitCode:   result.ExitCode,
+			DurationMs: result.Duration.Milliseconds(),
+		}
+		if result.Err != nil {
+			record.Error = result.Err.Error()
+			fmt.Printf("❌ Hook %s (%s): %v\n", result.Hook, result.Event.Type, result.Err)
+			if output := strings.TrimSpace(result.Output); output != "" {
+				fmt.Printf("   %s\n", strings.ReplaceAll(output, "\n", "\n   "))
+			}
+		} else {
+			fmt.Printf("✅ Hook %s (%s) completed in %s\n", result.Hook, result.Event.Type,
+				result.Duration.Round(time.Millisecond))
+		}
+		records[result.Event.Family] = append(records[result.Event.Family], record)
+	}
+	return records
+}
+
+// changeHookEvent builds the hook event for a changed address family
+func changeHookEvent(hostname, family string, status notifier.IPStatus, now time.Time) hooks.Event {
+	return hooks.Event{
+		Type:      hooks.EventChange,
+		Family:    family,
+		OldIP:     status.Previous,
+		NewIP:     status.Current,
+		Hostname:  hostname,
+		Service:   status.Service,
+		Timestamp: now.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+
+	"ip_detector/config"
+	"ip_detector/detector"
+)
+
+// configureServices registers custom detection services and applies the
+// per-service TLS settings from the configuration
+func configureServices(cfg *config.Config) error {
+	for _, custom := range cfg.CustomServices {
+		if custom.Name == "" || custom.IPv4URL == "" {
+			return fmt.Errorf("custom service needs a name and an IPv4 URL")
+		}
+		detector.RegisterService(detector.Service{
+			Name:    custom.Name,
+			IPv4URL: custom.IPv4URL,
+			IPv6URL: custom.IPv6URL,
+		})
+	}
+
+	for name, settings := range cfg.ServiceTLS {
+		tlsConfig, err := detector.BuildTLSConfig(detector.TLSOptions{
+			CAFile:     settings.CAFile,
+			CertFile:   settings.CertFile,
+			KeyFile:    settings.KeyFile,
+			MinVersion: settings.MinVersion,
+			Pins:       settings.Pins,
+			ServerName: settings.ServerName,
+		})
+		if err != nil {
+			return fmt.Errorf("invalid TLS settings for service %s: %w", name, err)
+		}
+		if err := detector.SetServiceTLS(name, tlsConfig); err != nil {
+			return fmt.Errorf("invalid TLS settings: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"ip_detector/notifier"
+)
+
+const (
+	// chatDiscoveryTimeout is how long the wizard waits for a message to the bot
+	chatDiscoveryTimeout = 2 * time.Minute
+	// chatDiscoveryPoll is the long-poll timeout of each getUpdates request
+	chatDiscoveryPoll = 10 * time.Second
+)
+
+// describeChat formats a chat for selection in the setup wizard
+func describeChat(chat notifier.TelegramChat) string {
+	var name string
+	switch {
+	case chat.Title != "":
+		name = fmt.Sprintf("%s %q", chat.Type, chat.Title)
+	case chat.Username != "":
+		name = fmt.Sprintf("%s chat with @%s", chat.Type, chat.Username)
+	case chat.FirstName != "":
+		name = fmt.Sprintf("%s chat with %s", chat.Type, chat.FirstName)
+	default:
+		name = chat.Type + " chat"
+	}
+	return fmt.Sprintf("%s (%d)", name, chat.ID)
+}
+
+// discoverChats returns the chats the bot received messages from. Pending
+// updates are returned right away; if there are none, it waits for a new
+// message until chatDiscoveryTimeout.
+func discoverChats(bot *notifier.TelegramNotifier) ([]notifier.TelegramChat, error) {
+	deadline := time.Now().Add(chatDiscoveryTimeout)
+	var offset int64
+	var poll time.Duration
+
+	for {
+		updates, err := bot.GetUpdates(offset, poll)
+		if err != nil {
+			return nil, err
+		}
+
+		var chats []notifier.TelegramChat
+		seen := make(map[int64]bool)
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			msg := update.Message
+			if msg == nil {
+				msg = update.ChannelPost
+			}
+			if msg == nil || seen[msg.Chat.ID] {
+				continue
+			}
+			seen[msg.Chat.ID] = true
+			chats = append(chats, msg.Chat)
+		}
+		if len(chats) > 0 {
+			return chats, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("no message received within %s", chatDiscoveryTimeout)
+		}
+		poll = chatDiscoveryPoll
+	}
+}
+
+// selectChatID waits for a message to the bot and lets the user pick the chat
+// it was sent in
+func selectChatID(reader *bufio.Reader, bot *notifier.TelegramNotifier, botName string) (string, error) {
+	fmt.Printf("\nSend a message to @%s now (for a group or channel, add the bot and post there).\n", botName)
+	fmt.Printf("Waiting up to %s...\n", chatDiscoveryTimeout)
+
+	chats, err := discoverChats(bot)
+	if err != nil {
+		return "", fmt.Errorf("failed to discover chat ID: %w", err)
+	}
+
+	if len(chats) == 1 {
+		fmt.Printf("✅ Found %s\n", describeChat(chats[0]))
+		return strconv.FormatInt(chats[0].ID, 10), nil
+	}
+
+	fmt.Println("\nMessages were received from several chats:")
+	for i, chat := range chats {
+		fmt.Printf("  %d. %s\n", i+1, describeChat(chat))
+	}
+	fmt.Printf("\nSelect a chat (1-%d): ", len(chats))
+	input, _ := reader.ReadString('\n')
+	idx, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil || idx < 1 || idx > len(chats) {
+		return "", fmt.Errorf("invalid chat selection")
+	}
+	return strconv.FormatInt(chats[idx-1].ID, 10), nil
+}
@@ -3,9 +3,11 @@ package storage
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
@@ -90,3 +92,17 @@ func Decrypt(encrypted string) (string, error) {
 
 	return string(plaintext), nil
 }
+
+// Fingerprint returns a keyed HMAC-SHA256 of value, hex-encoded, using the
+// machine-specific key. It identifies a secret such as a chat ID without
+// revealing it, even when the value is short enough to be guessed.
+func Fingerprint(value string) (string, error) {
+	key, err := deriveKey()
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("fingerprint:" + value))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	first, err := Fingerprint("-100200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Fingerprint("-100200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != again {
+		t.Errorf("fingerprint is not stable: %s != %s", first, again)
+	}
+
+	other, err := Fingerprint("-100201")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == other {
+		t.Errorf("different values have the same fingerprint %s", first)
+	}
+
+	plain := sha256.Sum256([]byte("-100200"))
+	if first == hex.EncodeToString(plain[:]) {
+		t.Errorf("fingerprint is an unkeyed SHA-256")
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	encrypted, err := Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encrypted == "secret" {
+		t.Fatal("Encrypt returned the plaintext")
+	}
+	plaintext, err := Decrypt(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext != "secret" {
+		t.Errorf("Decrypt = %q, want secret", plaintext)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"ip_detector/config"
+	"ip_detector/render"
+)
+
+// renderTemplates renders the configured template targets with the last known
+// addresses. Targets are only written and reloaded when their output changes.
+func renderTemplates(cfg *config.Config, hostname string) {
+	if len(cfg.Templates) == 0 {
+		return
+	}
+
+	targets := make([]render.Target, 0, len(cfg.Templates))
+	for _, tc := range cfg.Templates {
+		mode := uint64(0644)
+		if tc.Mode != "" {
+			parsed, err := strconv.ParseUint(tc.Mode, 8, 32)
+			if err != nil {
+				fmt.Printf("⚠️  Template %s: invalid mode %q\n", tc.Destination, tc.Mode)
+				continue
+			}
+			mode = parsed
+		}
+		targets = append(targets, render.Target{
+			Source:        tc.Source,
+			Destination:   tc.Destination,
+			Mode:          os.FileMode(mode),
+			ReloadCommand: tc.ReloadCommand,
+		})
+	}
+
+	data := render.Data{
+		Hostname:  hostname,
+		IPv4:      cfg.LastKnownIPv4,
+		IPv6:      cfg.LastKnownIPv6,
+		IPv4Label: cfg.LabelFor(cfg.LastKnownIPv4),
+		IPv6Label: cfg.LabelFor(cfg.LastKnownIPv6),
+	}
+	if changed, err := time.Parse(time.RFC3339, cfg.LastChanged); err == nil {
+		data.Timestamp = changed
+	}
+
+	for _, result := range render.Render(targets, data) {
+		switch {
+		case result.Err != nil:
+			fmt.Printf("❌ Template %s: %v\n", result.Destination, result.Err)
+		case result.Reloaded:
+			fmt.Printf("✅ Template %s updated and reloaded\n", result.Destination)
+		case result.Changed:
+			fmt.Printf("✅ Template %s updated\n", result.Destination)
+		}
+	}
+}